@@ -0,0 +1,177 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	MinPasswordLength int = 8
+	SessionCookie         = "session"
+	SessionDuration       = 30 * 24 * time.Hour
+)
+
+var accountNamePattern = regexp.MustCompile("^\\w{3,20}$")
+
+type Account struct {
+	Id           string        `json:"id"`
+	PasswordHash []byte        `json:"passwordHash"`
+	Created      time.Time     `json:"created"`
+	Rating       int           `json:"rating"`
+	History      []MatchRecord `json:"history"`
+	// Keyed by the challenge id
+	Challenges map[string]*ChallengeRecord `json:"challenges,omitempty"`
+}
+
+// Profile is the public part of the account
+type Profile struct {
+	Id      string    `json:"id"`
+	Created time.Time `json:"created"`
+	Rating  int       `json:"rating"`
+}
+
+type WrongAccountError struct {
+	reason string
+}
+
+func (e *WrongAccountError) Error() string {
+	return fmt.Sprintf("wrong account: %s", e.reason)
+}
+
+func (a *Account) profile() Profile {
+	return Profile{
+		Id:      a.Id,
+		Created: a.Created,
+		Rating:  a.Rating,
+	}
+}
+
+func (s *Store) createAccount(id string, password string) (*Account, error) {
+	if !accountNamePattern.MatchString(id) {
+		return nil, &WrongAccountError{"the name should be 3 to 20 letters, digits or underscores"}
+	}
+	if len(password) < MinPasswordLength {
+		return nil, &WrongAccountError{fmt.Sprintf("the password should be at least %d characters", MinPasswordLength)}
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	// Names differing only in case would be confused by the players
+	key := strings.ToLower(id)
+	if _, ok := s.data.Accounts[key]; ok {
+		return nil, &WrongAccountError{"the name is taken"}
+	}
+	account := &Account{
+		Id:           id,
+		PasswordHash: hash,
+		Created:      time.Now(),
+		Rating:       InitialRating,
+	}
+	s.data.Accounts[key] = account
+	return account.copy(), s.save()
+}
+
+// account returns a copy of the account, the accounts of the store are
+// changed under its mutex
+func (s *Store) account(id string) (*Account, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	account, ok := s.data.Accounts[strings.ToLower(id)]
+	if !ok {
+		return nil, false
+	}
+	return account.copy(), true
+}
+
+func (a *Account) copy() *Account {
+	copied := *a
+	copied.History = append([]MatchRecord{}, a.History...)
+	if a.Challenges != nil {
+		copied.Challenges = make(map[string]*ChallengeRecord)
+		for id, record := range a.Challenges {
+			r := *record
+			copied.Challenges[id] = &r
+		}
+	}
+	return &copied
+}
+
+func (s *Store) authenticate(id string, password string) (*Account, error) {
+	account, ok := s.account(id)
+	if !ok {
+		return nil, &WrongAccountError{"wrong name or password"}
+	}
+	err := bcrypt.CompareHashAndPassword(account.PasswordHash, []byte(password))
+	if err != nil {
+		return nil, &WrongAccountError{"wrong name or password"}
+	}
+	return account, nil
+}
+
+// newSession signs "<account>.<expiry>" with the secret of the store, the
+// token is checked without keeping the sessions around
+func (s *Store) newSession(account *Account) string {
+	payload := fmt.Sprintf("%s.%d", strings.ToLower(account.Id), time.Now().Add(SessionDuration).Unix())
+	return fmt.Sprintf("%s.%s", payload, s.sign(payload))
+}
+
+func (s *Store) sessionAccount(token string) (*Account, error) {
+	parsed := strings.Split(token, ".")
+	if len(parsed) != 3 {
+		return nil, &WrongAccountError{"malformed session"}
+	}
+	payload := parsed[0] + "." + parsed[1]
+	if !hmac.Equal([]byte(s.sign(payload)), []byte(parsed[2])) {
+		return nil, &WrongAccountError{"invalid session"}
+	}
+	expiry, err := strconv.ParseInt(parsed[1], 10, 64)
+	if err != nil || time.Now().Unix() > expiry {
+		return nil, &WrongAccountError{"expired session"}
+	}
+	account, ok := s.account(parsed[0])
+	if !ok {
+		return nil, &WrongAccountError{"unknown account"}
+	}
+	return account, nil
+}
+
+func (s *Store) sign(payload string) string {
+	s.mutex.Lock()
+	mac := hmac.New(sha256.New, s.data.SessionSecret)
+	s.mutex.Unlock()
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func newSessionSecret() ([]byte, error) {
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	return secret, err
+}
+
+// requestAccount finds the account of the session cookie or of the token
+// query parameter, which is used by the clients unable to send cookies.
+// There is no account and no error for the anonymous requests.
+func requestAccount(r *http.Request) (*Account, error) {
+	token := r.URL.Query().Get("token")
+	if cookie, err := r.Cookie(SessionCookie); err == nil && token == "" {
+		token = cookie.Value
+	}
+	if token == "" {
+		return nil, nil
+	}
+	return store.sessionAccount(token)
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// isAdmin tells if the request carries the admin token
+func isAdmin(r *http.Request) bool {
+	if config.AdminToken == "" {
+		return false
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(config.AdminToken)) == 1
+}
+
+type HubStatus string
+
+const (
+	Running  HubStatus = "running"
+	Paused   HubStatus = "paused"
+	Finished HubStatus = "finished"
+	// The state was found inconsistent, see Config.HaltOnViolation
+	Halted HubStatus = "halted"
+)
+
+type SeatOccupancy struct {
+	Seat    PlayerId `json:"seat"`
+	Account string   `json:"account,omitempty"`
+	Clients int      `json:"clients"`
+}
+
+type AdminHubData struct {
+	Name    string          `json:"name"`
+	Options GameOptions     `json:"options"`
+	Status  HubStatus       `json:"status"`
+	Started string          `json:"started"`
+	Seats   []SeatOccupancy `json:"seats"`
+}
+
+// AdminHubDump is everything known about the game, the hidden cards and the
+// seed included
+type AdminHubDump struct {
+	AdminHubData
+	Version  int                 `json:"version"`
+	State    *State              `json:"state"`
+	Piles    map[string][]string `json:"piles"`
+	Notation string              `json:"notation"`
+}
+
+type MaintenanceMessage struct {
+	Maintenance string `json:"maintenance"`
+}
+
+// command runs the function in the goroutine of the hub, which sends to the
+// state manager, and waits for it
+func (h *Hub) command(f func(stateManager *StateManager)) {
+	done := make(chan bool)
+	h.admin <- func(stateManager *StateManager) {
+		f(stateManager)
+		close(done)
+	}
+	<-done
+}
+
+func (h *Hub) status() HubStatus {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	switch {
+	case h.finished:
+		return Finished
+	case h.halted:
+		return Halted
+	case h.paused:
+		return Paused
+	default:
+		return Running
+	}
+}
+
+func (h *Hub) adminData() AdminHubData {
+	clients := make(map[PlayerId]int)
+	for _, client := range h.clientList() {
+		clients[client.playerId]++
+	}
+	accounts := h.seatAccounts()
+	seats := []SeatOccupancy{}
+	for i := 1; i <= h.options.Players; i++ {
+		seat := PlayerId(i)
+		seats = append(seats, SeatOccupancy{Seat: seat, Account: accounts[seat], Clients: clients[seat]})
+	}
+	return AdminHubData{
+		Name:    h.name,
+		Options: h.options,
+		Status:  h.status(),
+		Started: h.started.Format(time.RFC3339),
+		Seats:   seats,
+	}
+}
+
+func (h *Hub) dump() AdminHubDump {
+	dump := AdminHubDump{AdminHubData: h.adminData()}
+	h.command(func(stateManager *StateManager) {
+		version := make(chan int, 1)
+		snapshot := make(chan *State, 1)
+		stateManager.action <- &StateActionGetVersion{version: version}
+		stateManager.action <- &StateActionGetSnapshot{snapshot: snapshot}
+		dump.Version = <-version
+		dump.State = <-snapshot
+	})
+	dump.Piles = make(map[string][]string)
+	if dump.State == nil {
+		h.logger.Error("state not dumped")
+	} else {
+		for location, pile := range dump.State.piles {
+			if len(pile) > 0 {
+				dump.Piles[locationNames[location]] = pile
+			}
+		}
+	}
+	h.mutex.Lock()
+	dump.Notation = exportNotation(h.options, h.history)
+	h.mutex.Unlock()
+	return dump
+}
+
+func (h *Hub) setPaused(paused bool) {
+	h.mutex.Lock()
+	h.paused = paused
+	h.mutex.Unlock()
+	h.logger.Warn("pause changed by an operator", "paused", paused)
+}
+
+// forceEnd finishes the game, nothing is recorded but the result of the
+// tournament game which is forfeited to the winner
+func (h *Hub) forceEnd(winner PlayerId) bool {
+	h.mutex.Lock()
+	if h.finished {
+		h.mutex.Unlock()
+		return false
+	}
+	h.finished = true
+	h.mutex.Unlock()
+	text := "The game was ended by an operator"
+	gameOver := &StateActionGameOver{}
+	if h.tournament != nil {
+		text = fmt.Sprintf("The game was forfeited to player %d by an operator", winner)
+		gameOver.winner = winner
+	}
+	h.command(func(stateManager *StateManager) {
+		stateManager.action <- &StateActionResetActions{}
+		stateManager.action <- &StateActionAddLogEntry{entry: LogEntry{Text: text}}
+		stateManager.action <- gameOver
+		stateManager.action <- &StateActionGetState{}
+	})
+	finishedGames.WithLabelValues("ended").Inc()
+	h.logger.Warn("game ended by an operator", "winner", gameOver.winner)
+	if h.tournament != nil {
+		h.tournament.recordResult(h.name, h.seatAccounts()[winner])
+	}
+	return true
+}
+
+// kick disconnects the clients of the seat, they may connect again
+func (h *Hub) kick(seat PlayerId) int {
+	kicked := 0
+	for _, client := range h.clientList() {
+		if client.playerId == seat && h.removeClient(client) {
+			kicked++
+		}
+	}
+	h.logger.Warn("seat kicked by an operator", "seat", seat, "clients", kicked)
+	return kicked
+}
+
+// sendMaintenance queues the message to the clients right away, it doesn't
+// wait for the goroutines of the hub
+func (h *Hub) sendMaintenance(message []byte) {
+	for _, client := range h.clientList() {
+		h.deliver(client, message)
+	}
+}
+
+func sortedHubs() []*Hub {
+	hubsMutex.Lock()
+	defer hubsMutex.Unlock()
+	list := make([]*Hub, 0, len(hubs))
+	for _, hub := range hubs {
+		list = append(list, hub)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].name < list[j].name
+	})
+	return list
+}
+
+// routeAdmin serves the operator endpoints under /admin
+func routeAdmin(w http.ResponseWriter, r *http.Request) {
+	var adminHubPattern = regexp.MustCompile("^/admin/hubs/(\\w+)(/pause|/end|/kick|/log-level)?$")
+	var adminScenarioPattern = regexp.MustCompile("^/admin/hubs/(\\w+)/scenario$")
+	type PauseData struct {
+		Paused bool `json:"paused"`
+	}
+	type KickData struct {
+		Seat PlayerId `json:"seat"`
+	}
+	// The tournament games are forfeited to the winner
+	type EndData struct {
+		Winner PlayerId `json:"winner"`
+	}
+	type LogLevelData struct {
+		Level string `json:"level"`
+	}
+	if !isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	if r.URL.Path == "/admin/hubs" && r.Method == "GET" {
+		hubsList := []AdminHubData{}
+		for _, hub := range sortedHubs() {
+			hubsList = append(hubsList, hub.adminData())
+		}
+		writeJSON(w, r, hubsList)
+		return
+	}
+	if r.URL.Path == "/admin/broadcast" && r.Method == "POST" {
+		var data MaintenanceMessage
+		body, _ := ioutil.ReadAll(r.Body)
+		err := json.Unmarshal(body, &data)
+		if err != nil || data.Maintenance == "" {
+			http.Error(w, "Wrong request", http.StatusBadRequest)
+			return
+		}
+		message, err := json.Marshal(data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, hub := range sortedHubs() {
+			hub.sendMaintenance(message)
+		}
+		slog.Warn("maintenance message sent", "message", data.Maintenance)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if adminScenarioPattern.MatchString(r.URL.Path) && r.Method == "POST" {
+		name := adminScenarioPattern.FindStringSubmatch(r.URL.Path)[1]
+		var scenario Scenario
+		body, _ := ioutil.ReadAll(r.Body)
+		err := json.Unmarshal(body, &scenario)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		hub, err := newScenarioHub(name, &scenario, store)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err = addHub(name, hub)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if !adminHubPattern.MatchString(r.URL.Path) {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	matches := adminHubPattern.FindStringSubmatch(r.URL.Path)
+	hub, ok := findHub(matches[1])
+	if !ok {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	body, _ := ioutil.ReadAll(r.Body)
+	switch {
+	case matches[2] == "" && r.Method == "GET":
+		writeJSON(w, r, hub.dump())
+	case matches[2] == "/pause" && r.Method == "POST":
+		var data PauseData
+		err := json.Unmarshal(body, &data)
+		if err != nil {
+			http.Error(w, "Wrong request", http.StatusBadRequest)
+			return
+		}
+		hub.setPaused(data.Paused)
+		w.WriteHeader(http.StatusOK)
+	case matches[2] == "/end" && r.Method == "POST":
+		var data EndData
+		if len(body) > 0 && json.Unmarshal(body, &data) != nil {
+			http.Error(w, "Wrong request", http.StatusBadRequest)
+			return
+		}
+		if hub.tournament != nil && (data.Winner < FirstPlayer || int(data.Winner) > hub.options.Players) {
+			http.Error(w, "The tournament game needs the winner of the forfeit", http.StatusBadRequest)
+			return
+		}
+		if !hub.forceEnd(data.Winner) {
+			http.Error(w, "The game is over already", http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	case matches[2] == "/kick" && r.Method == "POST":
+		var data KickData
+		err := json.Unmarshal(body, &data)
+		if err != nil || data.Seat < FirstPlayer || int(data.Seat) > hub.options.Players {
+			http.Error(w, "Wrong request", http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, r, map[string]int{"kicked": hub.kick(data.Seat)})
+	case matches[2] == "/log-level" && r.Method == "POST":
+		var data LogLevelData
+		err := json.Unmarshal(body, &data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err = hub.logLevel.UnmarshalText([]byte(data.Level))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		hub.logger.Info("log level changed", "logLevel", hub.logLevel.Level())
+		w.WriteHeader(http.StatusOK)
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, value interface{}) {
+	result, err := json.Marshal(value)
+	if err != nil {
+		slog.Error("request failed", "path", r.URL.Path, "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write(result)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// An operator ending a tournament game forfeits it so the tournament goes on
+func TestForceEndForfeitsTheTournamentGame(t *testing.T) {
+	tournament, err := newTournament("forfeited", Swiss, 2, "alice", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, player := range []string{"alice", "bob"} {
+		err = tournament.register(player)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = tournament.start("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pairing := tournament.data().Pairings[0][0]
+	hub, ok := findHub(pairing.Hub)
+	if !ok {
+		t.Fatalf("no hub %s", pairing.Hub)
+	}
+	if !hub.forceEnd(SecondPlayer) {
+		t.Fatal("the game isn't ended")
+	}
+	if hub.forceEnd(SecondPlayer) {
+		t.Error("the game is ended twice")
+	}
+	data := tournament.data()
+	if winner := data.Pairings[0][0].Winner; winner != pairing.Players[1] {
+		t.Errorf("the winner is %q, want %q", winner, pairing.Players[1])
+	}
+	if len(data.Pairings) != 2 {
+		t.Errorf("%d rounds are paired, want 2", len(data.Pairings))
+	}
+}
+
+// The game ended by an operator isn't recorded again when it ends in play
+func TestGameOverAfterForceEnd(t *testing.T) {
+	store, err := newStore(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	account, err := store.createAccount("carol", "secret password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := GameOptions{Players: MinPlayers, Challenge: "raider"}
+	err = resolveSetup(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub := newHub("ended", options, store)
+	hub.accounts[FirstPlayer] = account.Id
+	go hub.run()
+	if !hub.forceEnd(FirstPlayer) {
+		t.Fatal("the game isn't ended")
+	}
+	hub.gameOver(FirstPlayer, NoTeam, newState(gameDeck(options), options))
+	if record := store.challengeRecord(account, "raider"); record.Wins != 0 || record.Losses != 0 {
+		t.Errorf("the challenge is recorded as %+v", record)
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CatalogCard describes a card for the clients so they don't have to keep
+// their own copy of the deck
+type CatalogCard struct {
+	Id        string           `json:"id"`
+	Name      string           `json:"name"`
+	Cost      int              `json:"cost"`
+	Qty       int              `json:"qty"`
+	Faction   Faction          `json:"faction"`
+	CardType  CardType         `json:"cardType"`
+	Defense   int              `json:"defense"`
+	Abilities []CatalogAbility `json:"abilities"`
+	Text      string           `json:"text"`
+}
+
+type CatalogAbility struct {
+	Id AbilityId `json:"id"`
+	// One of "beforePlay", "primary", "ally" and "scrap"
+	Group string `json:"group"`
+	// Activated abilities are used by the player with ActivateAbility
+	Activated bool `json:"activated"`
+	// The abilities disabled by using this one, only one of them is chosen
+	Alternatives []AbilityId     `json:"alternatives,omitempty"`
+	Effects      []CatalogEffect `json:"effects"`
+	Text         string          `json:"text"`
+}
+
+type CatalogEffect struct {
+	// A counter name or one of the requestEffects
+	Effect   string `json:"effect"`
+	Value    int    `json:"value,omitempty"`
+	Opponent bool   `json:"opponent,omitempty"`
+}
+
+// The effects of the actions requested from the player
+var requestEffects = map[UserAction]string{
+	ScrapCard:                "scrapHandOrDiscard",
+	ScrapCardTradeRow:        "scrapTradeRow",
+	ScrapCardInHand:          "scrapHand",
+	DestroyBaseForFree:       "destroyBase",
+	DestroyBaseBlobDestroyer: "destroyBaseAndScrapTradeRow",
+	AcquireShipForFree:       "acquireShip",
+	ActivateAbility:          "chooseOne",
+	ActivateBrainWorld:       "scrapAndDraw",
+	ActivateMechWorld:        "allyForAllFactions",
+	ActivateRecyclingStation: "discardAndDraw",
+	ActivateNeedle:           "copyShip",
+}
+
+var effectTexts = map[string]string{
+	"scrapHandOrDiscard":          "you may scrap a card in your hand or discard pile",
+	"scrapTradeRow":               "you may scrap a card in the trade row",
+	"scrapHand":                   "scrap a card in your hand",
+	"destroyBase":                 "you may destroy target base",
+	"destroyBaseAndScrapTradeRow": "you may destroy target base and/or scrap a card in the trade row",
+	"acquireShip":                 "acquire any ship for free and put it on top of your deck",
+	"chooseOne":                   "choose one",
+	"scrapAndDraw":                "scrap up to two cards from your hand and/or discard pile, draw a card for each card scrapped",
+	"allyForAllFactions":          "counts as an ally for all factions",
+	"discardAndDraw":              "discard up to two cards, then draw that many cards",
+	"copyShip":                    "copy another ship you've played this turn",
+	"drawWithBases":               "if you have two or more bases in play, draw two cards",
+	"shipCombat":                  "all of your ships get +1 combat",
+	"drawPerBlob":                 "draw a card for each Blob card that you've played this turn",
+}
+
+// getCatalog describes the cards from the effects the deck declares, it's
+// built once when the server starts
+func getCatalog() []CatalogCard {
+	deck := getDeck()
+	addGambits(deck)
+
+	catalog := make([]CatalogCard, 0, len(*deck))
+	for key, card := range *deck {
+		entry := CatalogCard{
+			Id:        key,
+			Name:      card.name,
+			Cost:      card.cost,
+			Qty:       card.qty,
+			Faction:   card.faction,
+			CardType:  card.cardType,
+			Defense:   card.defense,
+			Abilities: []CatalogAbility{},
+		}
+		for _, ability := range append(append(Abilities{}, card.beforePlay...), card.abilities...) {
+			effects := []CatalogEffect{}
+			for _, effect := range ability.effects {
+				effects = append(effects, CatalogEffect{
+					Effect:   effect.name,
+					Value:    effect.value,
+					Opponent: effect.opponent,
+				})
+			}
+			entry.Abilities = append(entry.Abilities, CatalogAbility{
+				Id:           ability.id,
+				Group:        abilityGroup(ability),
+				Activated:    ability.actionType == Activated,
+				Alternatives: ability.alternatives,
+				Effects:      effects,
+				Text:         abilityText(effects),
+			})
+		}
+		entry.Text = cardText(entry.Abilities)
+		catalog = append(catalog, entry)
+	}
+	sort.Slice(catalog, func(i, j int) bool {
+		return catalog[i].Id < catalog[j].Id
+	})
+	return catalog
+}
+
+func abilityGroup(ability *Ability) string {
+	switch {
+	case ability.id == Utilization:
+		return "scrap"
+	case ability.group == BeforePlay:
+		return "beforePlay"
+	case ability.group == Ally:
+		return "ally"
+	}
+	return "primary"
+}
+
+func abilityText(effects []CatalogEffect) string {
+	parts := []string{}
+	for _, effect := range effects {
+		switch {
+		case effect.Effect == "draw" && effect.Value == 1:
+			parts = append(parts, "draw a card")
+		case effect.Effect == "draw":
+			parts = append(parts, fmt.Sprintf("draw %d cards", effect.Value))
+		case effect.Effect == "discard" && effect.Opponent:
+			parts = append(parts, "target opponent discards a card")
+		case effect.Effect == "ships on top":
+			parts = append(parts, "you may put the next ship you acquire this turn on top of your deck")
+		case effectTexts[effect.Effect] != "":
+			parts = append(parts, effectTexts[effect.Effect])
+		default:
+			parts = append(parts, fmt.Sprintf("%+d %s", effect.Value, effect.Effect))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// cardText writes a line for every ability, the alternatives share a line
+// and the draws of the same group are counted together
+func cardText(abilities []CatalogAbility) string {
+	lines := []string{}
+	choice := false
+	for _, ability := range abilities {
+		choice = choice || len(ability.Alternatives) > 0
+	}
+	written := make(map[AbilityId]bool)
+	// The draws of the last line, if it's only a draw
+	draws := 0
+	for i, ability := range abilities {
+		switch {
+		case written[ability.Id]:
+			// On the line of its alternatives already
+		case len(ability.Alternatives) > 0:
+			texts := []string{}
+			for _, other := range abilities {
+				if other.Id == ability.Id || containsAbility(ability.Alternatives, other.Id) {
+					texts = append(texts, other.Text)
+					written[other.Id] = true
+				}
+			}
+			lines = append(lines, groupText(ability.Group, "Choose one: "+strings.Join(texts, " or ")))
+			draws = 0
+		case choice && len(ability.Effects) == 1 && ability.Effects[0].Effect == "chooseOne":
+			// The choice is told by the line of the alternatives
+		case isDraw(ability) && draws > 0 && abilities[i-1].Group == ability.Group:
+			draws += ability.Effects[0].Value
+			lines[len(lines)-1] = groupText(ability.Group, abilityText([]CatalogEffect{{Effect: "draw", Value: draws}}))
+		default:
+			lines = append(lines, groupText(ability.Group, ability.Text))
+			draws = 0
+			if isDraw(ability) {
+				draws = ability.Effects[0].Value
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// isDraw tells if the ability only draws cards
+func isDraw(ability CatalogAbility) bool {
+	return !ability.Activated && len(ability.Effects) == 1 && ability.Effects[0].Effect == "draw"
+}
+
+func containsAbility(ids []AbilityId, id AbilityId) bool {
+	for _, other := range ids {
+		if other == id {
+			return true
+		}
+	}
+	return false
+}
+
+func groupText(group string, text string) string {
+	switch group {
+	case "ally":
+		return "Ally: " + text
+	case "scrap":
+		return "Scrap: " + text
+	}
+	return text
+}
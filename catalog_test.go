@@ -0,0 +1,78 @@
+package main
+
+import (
+	"log/slog"
+	"reflect"
+	"testing"
+)
+
+// The effects which depend on the game aren't seen on an empty state
+var gameEffects = map[string]bool{
+	"drawWithBases": true,
+	"shipCombat":    true,
+	"drawPerBlob":   true,
+}
+
+// The effects the deck declares are the ones the actions have on an empty
+// state, the first seat owns the card
+func TestDeclaredEffects(t *testing.T) {
+	deck := getDeck()
+	addGambits(deck)
+	options := GameOptions{Players: MinPlayers}
+	err := resolveSetup(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := newState(deck, options)
+	for key, card := range *deck {
+		for i, ability := range append(append(Abilities{}, card.beforePlay...), card.abilities...) {
+			effects, alternatives := actionEffects(ability, key+"_1", state)
+			declared := []Effect{}
+			for _, effect := range ability.effects {
+				if !gameEffects[effect.name] {
+					declared = append(declared, effect)
+				}
+			}
+			if !reflect.DeepEqual(effects, declared) {
+				t.Errorf("ability %d of %s has the effects %+v, declared %+v", i, key, effects, declared)
+			}
+			if !reflect.DeepEqual(alternatives, append([]AbilityId{}, ability.alternatives...)) {
+				t.Errorf("ability %d of %s disables %v, declared %v", i, key, alternatives, ability.alternatives)
+			}
+		}
+	}
+}
+
+func actionEffects(ability *Ability, cardId string, state *State) ([]Effect, []AbilityId) {
+	player := FirstPlayer
+	if ability.player == Opponent {
+		player = SecondPlayer
+	}
+	effects := []Effect{}
+	alternatives := []AbilityId{}
+	for _, action := range ability.actions(player, cardId, state, slog.Default()) {
+		switch a := action.(type) {
+		case *StateActionChangeCounterValue:
+			name, ok := counterNames[a.counter]
+			if ok && a.operation == Increase {
+				effects = append(effects, Effect{name: name, value: a.value, opponent: a.player != FirstPlayer})
+			}
+		case *StateActionTopCard:
+			last := len(effects) - 1
+			if last >= 0 && effects[last].name == "draw" {
+				effects[last].value += 1
+				continue
+			}
+			effects = append(effects, Effect{name: "draw", value: 1})
+		case *StateActionRequestUserAction:
+			if name, ok := requestEffects[a.action]; ok {
+				effects = append(effects, Effect{name: name})
+			}
+		case *StateActionDisableActivatedAbility:
+			if a.cardId == cardId {
+				alternatives = append(alternatives, a.abilityId)
+			}
+		}
+	}
+	return effects, alternatives
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The scripted opponent of a solo challenge always takes the second seat
+const ChallengeSeat PlayerId = SecondPlayer
+
+type ChallengeRuleType int
+
+const (
+	// Deal Value damage to the player
+	DealDamage ChallengeRuleType = iota
+	// Acquire Value most expensive cards from the TradeRow
+	BuyMostExpensive
+	// Scrap Value cheapest cards from the TradeRow
+	ScrapCheapest
+	// Gain Value authority
+	GainAuthority
+)
+
+type ChallengeRule struct {
+	Type  ChallengeRuleType `json:"type"`
+	Value int               `json:"value"`
+}
+
+type Challenge struct {
+	Id          string          `json:"id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Authority   int             `json:"authority"`
+	Rules       []ChallengeRule `json:"rules"`
+}
+
+func getChallenges() map[string]*Challenge {
+	challenges := make(map[string]*Challenge)
+	for _, challenge := range []*Challenge{
+		&Challenge{
+			Id:          "raider",
+			Name:        "Raider",
+			Description: "Each turn deals 3 damage and buys the most expensive card in the trade row",
+			Authority:   40,
+			Rules: []ChallengeRule{
+				ChallengeRule{Type: DealDamage, Value: 3},
+				ChallengeRule{Type: BuyMostExpensive, Value: 1},
+			},
+		},
+		&Challenge{
+			Id:          "blockade",
+			Name:        "Blockade",
+			Description: "Each turn deals 2 damage and scraps the two cheapest cards in the trade row",
+			Authority:   50,
+			Rules: []ChallengeRule{
+				ChallengeRule{Type: DealDamage, Value: 2},
+				ChallengeRule{Type: ScrapCheapest, Value: 2},
+			},
+		},
+		&Challenge{
+			Id:          "juggernaut",
+			Name:        "Juggernaut",
+			Description: "Each turn deals 4 damage and gains 2 authority",
+			Authority:   60,
+			Rules: []ChallengeRule{
+				ChallengeRule{Type: DealDamage, Value: 4},
+				ChallengeRule{Type: GainAuthority, Value: 2},
+			},
+		},
+		&Challenge{
+			Id:          "warlord",
+			Name:        "Warlord",
+			Description: "Each turn deals 5 damage and buys the two most expensive cards in the trade row",
+			Authority:   70,
+			Rules: []ChallengeRule{
+				ChallengeRule{Type: DealDamage, Value: 5},
+				ChallengeRule{Type: BuyMostExpensive, Value: 2},
+			},
+		},
+	} {
+		challenges[challenge.Id] = challenge
+	}
+	return challenges
+}
+
+// challengeTurn plays the whole turn of the scripted opponent, which holds
+// the turn already, and passes the turn back to the player
+func (m *Middleware) challengeTurn(challenge *Challenge, player PlayerId, state *State, actions *[]StateAction) {
+	market := newChallengeMarket(*m.deck, state)
+	damage := 0
+	for _, rule := range challenge.Rules {
+		switch rule.Type {
+		case DealDamage:
+			damage += rule.Value
+		case BuyMostExpensive:
+			for i := 0; i < rule.Value && len(market.tradeRow) > 0; i++ {
+				id := market.take(0)
+				m.challengeLog(fmt.Sprintf("%s bought %s", challenge.Name, m.cardName(id)), actions)
+				m.moveCard(id, TradeRow, seatLocations[ChallengeSeat].Discard, actions)
+				m.topCard(TradeDeck, TradeRow, actions)
+			}
+		case ScrapCheapest:
+			for i := 0; i < rule.Value && len(market.tradeRow) > 0; i++ {
+				id := market.take(len(market.tradeRow) - 1)
+				m.challengeLog(fmt.Sprintf("%s scrapped %s from the trade row", challenge.Name, m.cardName(id)), actions)
+				m.moveCard(id, TradeRow, ScrapHeap, actions)
+				m.topCard(TradeDeck, TradeRow, actions)
+			}
+		case GainAuthority:
+			m.challengeLog(fmt.Sprintf("%s gained %d authority", challenge.Name, rule.Value), actions)
+			m.changeCounterValue(ChallengeSeat, Increase, Authority, rule.Value, actions)
+		}
+	}
+	if damage > 0 {
+		m.challengeLog(fmt.Sprintf("%s dealt %d damage to %s", challenge.Name, damage, m.playerName(player)), actions)
+		m.damage(player, damage, state, actions)
+		if state.counters(player).Authority-damage <= 0 {
+			// The game is over, the turn stays with the challenge
+			return
+		}
+	}
+	*actions = append(*actions, &StateActionChangeTurn{})
+	if state.counters(player).Discard > 0 {
+		m.requestUserAction(player, DiscardCard, actions)
+	} else {
+		m.requestUserAction(player, Start, actions)
+	}
+}
+
+// challengeMarket follows the trade row through the turn of the challenge,
+// the actions aren't applied until the turn is over
+type challengeMarket struct {
+	deck      map[string]*CardEntry
+	tradeRow  Pile
+	tradeDeck Pile
+}
+
+func newChallengeMarket(deck map[string]*CardEntry, state *State) *challengeMarket {
+	market := &challengeMarket{
+		deck:      deck,
+		tradeRow:  state.pile(TradeRow),
+		tradeDeck: state.pile(TradeDeck),
+	}
+	market.sort()
+	return market
+}
+
+// sort puts the most expensive first, ids make the order stable for the
+// same cost
+func (c *challengeMarket) sort() {
+	sort.Slice(c.tradeRow, func(i, j int) bool {
+		a := c.deck[strings.Split(c.tradeRow[i], "_")[0]].cost
+		b := c.deck[strings.Split(c.tradeRow[j], "_")[0]].cost
+		if a != b {
+			return a > b
+		}
+		return c.tradeRow[i] < c.tradeRow[j]
+	})
+}
+
+// take removes the card from the trade row and reveals the top of the trade
+// deck in its place, the way the state manager will
+func (c *challengeMarket) take(position int) string {
+	id := c.tradeRow[position]
+	c.tradeRow = append(c.tradeRow[:position], c.tradeRow[position+1:]...)
+	if revealed, ok := c.tradeDeck.top(); ok {
+		c.tradeDeck = c.tradeDeck[:len(c.tradeDeck)-1]
+		c.tradeRow = append(c.tradeRow, revealed)
+		c.sort()
+	}
+	return id
+}
+
+func (m *Middleware) challengeLog(text string, actions *[]StateAction) {
+	m.log(LogEntry{
+		Player: ChallengeSeat,
+		Text:   text,
+	}, actions)
+}
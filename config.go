@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var configPath = flag.String("config", "", "YAML file of the server configuration")
+
+// The environment variables are the flag names in capitals with this prefix,
+// STARREALMS_MAX_HUBS for -max-hubs
+const envPrefix = "STARREALMS_"
+
+type DefaultGameConfig struct {
+	Players    int    `yaml:"players"`
+	Format     string `yaml:"format"`
+	Gambits    bool   `yaml:"gambits"`
+	Unrated    bool   `yaml:"unrated"`
+	Visibility string `yaml:"visibility"`
+}
+
+type Config struct {
+	Addr  string `yaml:"addr"`
+	Store string `yaml:"store"`
+	// No limit when 0
+	MaxHubs int `yaml:"maxHubs"`
+	// Origins of the other pages allowed to open the websockets, any with "*"
+	AllowedOrigins []string `yaml:"allowedOrigins"`
+	CORSOrigin     string   `yaml:"corsOrigin"`
+	// Time allowed to write a message to the peer
+	WriteWait time.Duration `yaml:"writeWait"`
+	// Time allowed to read the next pong message from the peer
+	PongWait time.Duration `yaml:"pongWait"`
+	// Maximum message size allowed from peer
+	MaxMessageSize  int64             `yaml:"maxMessageSize"`
+	LogFormat       string            `yaml:"logFormat"`
+	LogLevel        string            `yaml:"logLevel"`
+	AdminToken      string            `yaml:"adminToken"`
+	CheckInvariants bool              `yaml:"checkInvariants"`
+	HaltOnViolation bool              `yaml:"haltOnViolation"`
+	DefaultGame     DefaultGameConfig `yaml:"defaultGame"`
+}
+
+var config = defaultConfig()
+
+func defaultConfig() Config {
+	return Config{
+		Addr:           ":8080",
+		Store:          "store.json",
+		AllowedOrigins: []string{},
+		CORSOrigin:     "null",
+		WriteWait:      10 * time.Second,
+		PongWait:       60 * time.Second,
+		MaxMessageSize: 512,
+		LogFormat:      "text",
+		LogLevel:       "info",
+		DefaultGame: DefaultGameConfig{
+			Players:    MinPlayers,
+			Format:     formatNames[FreeForAll],
+			Visibility: visibilityNames[Public],
+		},
+	}
+}
+
+type WrongConfigError struct {
+	reason string
+}
+
+func (e *WrongConfigError) Error() string {
+	return fmt.Sprintf("wrong config: %s", e.reason)
+}
+
+// configOption is a setting which may be given by a flag or an environment
+// variable
+type configOption struct {
+	name    string
+	usage   string
+	boolean bool
+	set     func(c *Config, value string) error
+	// The value of the flag if it was given
+	flagValue *string
+}
+
+var configOptions = []*configOption{
+	stringOption("addr", "http service address", func(c *Config) *string { return &c.Addr }),
+	stringOption("store", "local store file", func(c *Config) *string { return &c.Store }),
+	intOption("max-hubs", "most unfinished hubs run at once, no limit when 0", func(c *Config) *int { return &c.MaxHubs }),
+	{name: "allowed-origins", usage: "comma separated other origins allowed to open the websockets, any with *", set: func(c *Config, value string) error {
+		c.AllowedOrigins = []string{}
+		for _, origin := range strings.Split(value, ",") {
+			if origin = strings.TrimSpace(origin); origin != "" {
+				c.AllowedOrigins = append(c.AllowedOrigins, origin)
+			}
+		}
+		return nil
+	}},
+	stringOption("cors-origin", "Access-Control-Allow-Origin of the responses", func(c *Config) *string { return &c.CORSOrigin }),
+	durationOption("write-wait", "time allowed to write a message to the client", func(c *Config) *time.Duration { return &c.WriteWait }),
+	durationOption("pong-wait", "time allowed to read the next pong from the client", func(c *Config) *time.Duration { return &c.PongWait }),
+	{name: "max-message-size", usage: "largest message read from the clients, in bytes", set: func(c *Config, value string) error {
+		size, err := strconv.ParseInt(value, 10, 64)
+		c.MaxMessageSize = size
+		return err
+	}},
+	stringOption("log-format", "format of the logs, text or json", func(c *Config) *string { return &c.LogFormat }),
+	stringOption("log-level", "lowest level logged: debug, info, warn or error", func(c *Config) *string { return &c.LogLevel }),
+	stringOption("admin-token", "bearer token of the operator endpoints, they are off without it", func(c *Config) *string { return &c.AdminToken }),
+	boolOption("check-invariants", "verify the state of the games after every action", func(c *Config) *bool { return &c.CheckInvariants }),
+	boolOption("halt-on-violation", "stop the game whose state is found inconsistent", func(c *Config) *bool { return &c.HaltOnViolation }),
+	intOption("default-players", "players of the new games which don't say", func(c *Config) *int { return &c.DefaultGame.Players }),
+	stringOption("default-format", "format of the new games which don't say", func(c *Config) *string { return &c.DefaultGame.Format }),
+	boolOption("default-gambits", "deal the gambits in the new games which don't say", func(c *Config) *bool { return &c.DefaultGame.Gambits }),
+	boolOption("default-unrated", "leave the ratings alone in the new games which don't say", func(c *Config) *bool { return &c.DefaultGame.Unrated }),
+	stringOption("default-visibility", "visibility of the new games which don't say: public, unlisted or private", func(c *Config) *string { return &c.DefaultGame.Visibility }),
+}
+
+func stringOption(name string, usage string, field func(c *Config) *string) *configOption {
+	return &configOption{name: name, usage: usage, set: func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}}
+}
+
+func intOption(name string, usage string, field func(c *Config) *int) *configOption {
+	return &configOption{name: name, usage: usage, set: func(c *Config, value string) error {
+		number, err := strconv.Atoi(value)
+		*field(c) = number
+		return err
+	}}
+}
+
+func boolOption(name string, usage string, field func(c *Config) *bool) *configOption {
+	return &configOption{name: name, usage: usage, boolean: true, set: func(c *Config, value string) error {
+		enabled, err := strconv.ParseBool(value)
+		*field(c) = enabled
+		return err
+	}}
+}
+
+func durationOption(name string, usage string, field func(c *Config) *time.Duration) *configOption {
+	return &configOption{name: name, usage: usage, set: func(c *Config, value string) error {
+		duration, err := time.ParseDuration(value)
+		*field(c) = duration
+		return err
+	}}
+}
+
+func (o *configOption) envName() string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(o.name, "-", "_"))
+}
+
+// The flags are only recorded when parsed, they are applied over the file and
+// the environment
+func (o *configOption) String() string {
+	if o == nil || o.flagValue == nil {
+		return ""
+	}
+	return *o.flagValue
+}
+
+func (o *configOption) Set(value string) error {
+	if o.boolean {
+		if _, err := strconv.ParseBool(value); err != nil {
+			return err
+		}
+	}
+	o.flagValue = &value
+	return nil
+}
+
+func (o *configOption) IsBoolFlag() bool {
+	return o.boolean
+}
+
+func init() {
+	for _, option := range configOptions {
+		flag.Var(option, option.name, option.usage)
+	}
+}
+
+// loadConfig reads the defaults, the file, the environment and the flags
+// given, each over the one before, and validates the result
+func loadConfig(path string) (Config, error) {
+	c := defaultConfig()
+	if path == "" {
+		path = os.Getenv(envPrefix + "CONFIG")
+	}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return c, err
+		}
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(&c)
+		if err != nil && err != io.EOF {
+			return c, &WrongConfigError{fmt.Sprintf("%s: %v", path, err)}
+		}
+	}
+	for _, option := range configOptions {
+		value, ok := os.LookupEnv(option.envName())
+		if !ok {
+			continue
+		}
+		err := option.set(&c, value)
+		if err != nil {
+			return c, &WrongConfigError{fmt.Sprintf("%s: %v", option.envName(), err)}
+		}
+	}
+	for _, option := range configOptions {
+		if option.flagValue == nil {
+			continue
+		}
+		err := option.set(&c, *option.flagValue)
+		if err != nil {
+			return c, &WrongConfigError{fmt.Sprintf("-%s: %v", option.name, err)}
+		}
+	}
+	return c, c.validate()
+}
+
+func (c Config) validate() error {
+	if c.Addr == "" {
+		return &WrongConfigError{"addr is empty"}
+	}
+	if c.Store == "" {
+		return &WrongConfigError{"store is empty"}
+	}
+	if c.MaxHubs < 0 {
+		return &WrongConfigError{"maxHubs should not be negative"}
+	}
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		parsed, err := url.Parse(origin)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" || parsed.Path != "" {
+			return &WrongConfigError{fmt.Sprintf("allowed origin %q should be a scheme and a host", origin)}
+		}
+	}
+	if c.WriteWait <= 0 {
+		return &WrongConfigError{"writeWait should be positive"}
+	}
+	if c.PongWait <= 0 {
+		return &WrongConfigError{"pongWait should be positive"}
+	}
+	if c.MaxMessageSize <= 0 {
+		return &WrongConfigError{"maxMessageSize should be positive"}
+	}
+	if c.LogFormat != "text" && c.LogFormat != "json" {
+		return &WrongConfigError{fmt.Sprintf("unknown log format %q", c.LogFormat)}
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		return &WrongConfigError{fmt.Sprintf("unknown log level %q", c.LogLevel)}
+	}
+	format, ok := c.DefaultGame.format()
+	if !ok {
+		return &WrongConfigError{fmt.Sprintf("unknown default format %q", c.DefaultGame.Format)}
+	}
+	if err := validateSeating(c.DefaultGame.Players, format); err != nil {
+		return &WrongConfigError{fmt.Sprintf("default game: %v", err)}
+	}
+	if _, ok := c.DefaultGame.visibility(); !ok {
+		return &WrongConfigError{fmt.Sprintf("unknown default visibility %q", c.DefaultGame.Visibility)}
+	}
+	return nil
+}
+
+func (g DefaultGameConfig) format() (GameFormat, bool) {
+	for format, name := range formatNames {
+		if name == g.Format {
+			return format, true
+		}
+	}
+	return FreeForAll, false
+}
+
+func (g DefaultGameConfig) visibility() (Visibility, bool) {
+	for visibility, name := range visibilityNames {
+		if name == g.Visibility {
+			return visibility, true
+		}
+	}
+	return Public, false
+}
+
+// Send pings to peer with this period. Must be less than pongWait.
+func (c Config) pingPeriod() time.Duration {
+	return (c.PongWait * 9) / 10
+}
+
+// checkOrigin lets the websockets of the same origin and of the allowed
+// origins through, the clients which aren't browsers send no origin
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	parsed, err := url.Parse(origin)
+	if err == nil && strings.EqualFold(parsed.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range config.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// LogValue is the config without the secrets
+func (c Config) LogValue() slog.Value {
+	adminToken := ""
+	if c.AdminToken != "" {
+		adminToken = "REDACTED"
+	}
+	return slog.GroupValue(
+		slog.String("addr", c.Addr),
+		slog.String("store", c.Store),
+		slog.Int("maxHubs", c.MaxHubs),
+		slog.String("allowedOrigins", strings.Join(c.AllowedOrigins, ",")),
+		slog.String("corsOrigin", c.CORSOrigin),
+		slog.String("writeWait", c.WriteWait.String()),
+		slog.String("pongWait", c.PongWait.String()),
+		slog.Int64("maxMessageSize", c.MaxMessageSize),
+		slog.String("logFormat", c.LogFormat),
+		slog.String("logLevel", c.LogLevel),
+		slog.String("adminToken", adminToken),
+		slog.Bool("checkInvariants", c.CheckInvariants),
+		slog.Bool("haltOnViolation", c.HaltOnViolation),
+		slog.Group("defaultGame",
+			slog.Int("players", c.DefaultGame.Players),
+			slog.String("format", c.DefaultGame.Format),
+			slog.Bool("gambits", c.DefaultGame.Gambits),
+			slog.Bool("unrated", c.DefaultGame.Unrated),
+			slog.String("visibility", c.DefaultGame.Visibility),
+		),
+	)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+// The pages of other sites open no websocket unless their origin is allowed
+func TestCheckOrigin(t *testing.T) {
+	saved := config.AllowedOrigins
+	defer func() { config.AllowedOrigins = saved }()
+	cases := []struct {
+		allowed []string
+		origin  string
+		want    bool
+	}{
+		{[]string{}, "", true},
+		{[]string{}, "http://example.com", true},
+		{[]string{}, "http://evil.com", false},
+		{[]string{"http://friend.com"}, "http://friend.com", true},
+		{[]string{"http://friend.com"}, "http://evil.com", false},
+		{[]string{"*"}, "http://evil.com", true},
+	}
+	for _, c := range cases {
+		config.AllowedOrigins = c.allowed
+		r := httptest.NewRequest("GET", "http://example.com/ws", nil)
+		if c.origin != "" {
+			r.Header.Set("Origin", c.origin)
+		}
+		if got := checkOrigin(r); got != c.want {
+			t.Errorf("origin %q allowed by %v: got %v, want %v", c.origin, c.allowed, got, c.want)
+		}
+	}
+}
@@ -1,6 +1,6 @@
 package main
 
-import "log"
+import "log/slog"
 
 type Faction int
 
@@ -18,6 +18,7 @@ const (
 	Ship CardType = iota
 	Base
 	Outpost
+	Gambit
 )
 
 type AbilityActionType int
@@ -67,10 +68,23 @@ type Ability struct {
 	actionType AbilityActionType
 	id         AbilityId
 	player     PlayerPointer
-	actions    func(PlayerId, string, *State) []StateAction
+	actions    func(PlayerId, string, *State, *slog.Logger) []StateAction
+	// What the actions do, the catalog describes the card with them
+	effects []Effect
+	// The activated abilities of the card the actions disable
+	alternatives []AbilityId
+}
+
+// Effect is a counter the ability increases or one of the requestEffects,
+// the effects depending on the game have names of their own
+type Effect struct {
+	name     string
+	value    int
+	opponent bool
 }
 
 type CardEntry struct {
+	name       string
 	cost       int
 	qty        int
 	defense    int
@@ -140,8 +154,8 @@ func getDeck() *map[string]*CardEntry {
 	return &deck
 }
 
-func changeCounter(operation Operation, counter Counter, value int) func(PlayerId, string, *State) []StateAction {
-	return func(player PlayerId, cardId string, state *State) []StateAction {
+func changeCounter(operation Operation, counter Counter, value int) func(PlayerId, string, *State, *slog.Logger) []StateAction {
+	return func(player PlayerId, cardId string, state *State, logger *slog.Logger) []StateAction {
 		return []StateAction{
 			&StateActionChangeCounterValue{
 				player:    player,
@@ -153,8 +167,8 @@ func changeCounter(operation Operation, counter Counter, value int) func(PlayerI
 	}
 }
 
-func actionRequest(action UserAction) func(PlayerId, string, *State) []StateAction {
-	return func(player PlayerId, cardId string, state *State) []StateAction {
+func actionRequest(action UserAction) func(PlayerId, string, *State, *slog.Logger) []StateAction {
+	return func(player PlayerId, cardId string, state *State, logger *slog.Logger) []StateAction {
 		return []StateAction{
 			&StateActionRequestUserAction{
 				player: player,
@@ -165,17 +179,17 @@ func actionRequest(action UserAction) func(PlayerId, string, *State) []StateActi
 	}
 }
 
-func drawCard(player PlayerId, cardId string, state *State) []StateAction {
+func drawCard(player PlayerId, cardId string, state *State, logger *slog.Logger) []StateAction {
 	currentDeck, err := locationByPointer(CurrentDeck, player)
 	if err != nil {
 		// TODO: handle exception
-		log.Println(err)
+		logger.Warn("ability not applied", "err", err)
 		return []StateAction{}
 	}
 	currentHand, err := locationByPointer(CurrentHand, player)
 	if err != nil {
 		// TODO: handle exception
-		log.Println(err)
+		logger.Warn("ability not applied", "err", err)
 		return []StateAction{}
 	}
 	return []StateAction{
@@ -188,6 +202,7 @@ func drawCard(player PlayerId, cardId string, state *State) []StateAction {
 
 func scout() *CardEntry {
 	return &CardEntry{
+		name:    "Scout",
 		qty:     16,
 		faction: Unaligned,
 		abilities: []*Ability{
@@ -195,6 +210,7 @@ func scout() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Trade, 1),
+				effects: []Effect{{name: "trade", value: 1}},
 			},
 		},
 		cardType: Ship,
@@ -203,6 +219,7 @@ func scout() *CardEntry {
 
 func viper() *CardEntry {
 	return &CardEntry{
+		name:    "Viper",
 		qty:     4,
 		faction: Unaligned,
 		abilities: []*Ability{
@@ -210,6 +227,7 @@ func viper() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 1),
+				effects: []Effect{{name: "combat", value: 1}},
 			},
 		},
 		cardType: Ship,
@@ -218,6 +236,7 @@ func viper() *CardEntry {
 
 func explorer() *CardEntry {
 	return &CardEntry{
+		name:    "Explorer",
 		cost:    2,
 		qty:     10,
 		faction: Unaligned,
@@ -226,6 +245,7 @@ func explorer() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Trade, 2),
+				effects: []Effect{{name: "trade", value: 2}},
 			},
 			&Ability{
 				group:      Primary,
@@ -233,6 +253,7 @@ func explorer() *CardEntry {
 				id:         Utilization,
 				player:     Current,
 				actions:    changeCounter(Increase, Combat, 2),
+				effects:    []Effect{{name: "combat", value: 2}},
 			},
 		},
 		cardType: Ship,
@@ -241,6 +262,7 @@ func explorer() *CardEntry {
 
 func blobFighter() *CardEntry {
 	return &CardEntry{
+		name:    "Blob Fighter",
 		cost:    1,
 		qty:     3,
 		faction: Blob,
@@ -249,11 +271,13 @@ func blobFighter() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 3),
+				effects: []Effect{{name: "combat", value: 3}},
 			},
 			&Ability{
 				group:   Ally,
 				player:  Current,
 				actions: drawCard,
+				effects: []Effect{{name: "draw", value: 1}},
 			},
 		},
 		cardType: Ship,
@@ -262,6 +286,7 @@ func blobFighter() *CardEntry {
 
 func battleBlob() *CardEntry {
 	return &CardEntry{
+		name:     "Battle Blob",
 		cost:     6,
 		qty:      1,
 		faction:  Blob,
@@ -271,6 +296,7 @@ func battleBlob() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 8),
+				effects: []Effect{{name: "combat", value: 8}},
 			},
 			&Ability{
 				group:      Primary,
@@ -278,11 +304,13 @@ func battleBlob() *CardEntry {
 				id:         Utilization,
 				player:     Current,
 				actions:    changeCounter(Increase, Combat, 4),
+				effects:    []Effect{{name: "combat", value: 4}},
 			},
 			&Ability{
 				group:   Ally,
 				player:  Current,
 				actions: drawCard,
+				effects: []Effect{{name: "draw", value: 1}},
 			},
 		},
 	}
@@ -290,6 +318,7 @@ func battleBlob() *CardEntry {
 
 func mothership() *CardEntry {
 	return &CardEntry{
+		name:     "Mothership",
 		cost:     7,
 		qty:      1,
 		faction:  Blob,
@@ -299,16 +328,19 @@ func mothership() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 6),
+				effects: []Effect{{name: "combat", value: 6}},
 			},
 			&Ability{
 				group:   Primary,
 				player:  Current,
 				actions: drawCard,
+				effects: []Effect{{name: "draw", value: 1}},
 			},
 			&Ability{
 				group:   Ally,
 				player:  Current,
 				actions: drawCard,
+				effects: []Effect{{name: "draw", value: 1}},
 			},
 		},
 	}
@@ -316,6 +348,7 @@ func mothership() *CardEntry {
 
 func tradePod() *CardEntry {
 	return &CardEntry{
+		name:    "Trade Pod",
 		cost:    2,
 		qty:     2,
 		faction: Blob,
@@ -324,11 +357,13 @@ func tradePod() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Trade, 3),
+				effects: []Effect{{name: "trade", value: 3}},
 			},
 			&Ability{
 				group:   Ally,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 2),
+				effects: []Effect{{name: "combat", value: 2}},
 			},
 		},
 		cardType: Ship,
@@ -337,6 +372,7 @@ func tradePod() *CardEntry {
 
 func ram() *CardEntry {
 	return &CardEntry{
+		name:    "Ram",
 		cost:    3,
 		qty:     2,
 		faction: Blob,
@@ -345,6 +381,7 @@ func ram() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 5),
+				effects: []Effect{{name: "combat", value: 5}},
 			},
 			&Ability{
 				group:      Primary,
@@ -352,11 +389,13 @@ func ram() *CardEntry {
 				id:         Utilization,
 				player:     Current,
 				actions:    changeCounter(Increase, Trade, 3),
+				effects:    []Effect{{name: "trade", value: 3}},
 			},
 			&Ability{
 				group:   Ally,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 2),
+				effects: []Effect{{name: "combat", value: 2}},
 			},
 		},
 		cardType: Ship,
@@ -366,6 +405,7 @@ func ram() *CardEntry {
 
 func theHive() *CardEntry {
 	return &CardEntry{
+		name:    "The Hive",
 		cost:    5,
 		qty:     1,
 		faction: Blob,
@@ -374,11 +414,13 @@ func theHive() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 3),
+				effects: []Effect{{name: "combat", value: 3}},
 			},
 			&Ability{
 				group:   Ally,
 				player:  Current,
 				actions: drawCard,
+				effects: []Effect{{name: "draw", value: 1}},
 			},
 		},
 		cardType: Base,
@@ -388,6 +430,7 @@ func theHive() *CardEntry {
 
 func blobWheel() *CardEntry {
 	return &CardEntry{
+		name:    "Blob Wheel",
 		cost:    3,
 		qty:     3,
 		faction: Blob,
@@ -396,6 +439,7 @@ func blobWheel() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 1),
+				effects: []Effect{{name: "combat", value: 1}},
 			},
 			&Ability{
 				group:      Primary,
@@ -403,6 +447,7 @@ func blobWheel() *CardEntry {
 				id:         Utilization,
 				player:     Current,
 				actions:    changeCounter(Increase, Trade, 3),
+				effects:    []Effect{{name: "trade", value: 3}},
 			},
 		},
 		cardType: Base,
@@ -412,6 +457,7 @@ func blobWheel() *CardEntry {
 
 func battlePod() *CardEntry {
 	return &CardEntry{
+		name:     "Battle Pod",
 		cost:     2,
 		qty:      2,
 		faction:  Blob,
@@ -421,6 +467,7 @@ func battlePod() *CardEntry {
 				group:   BeforePlay,
 				player:  Current,
 				actions: actionRequest(ScrapCardTradeRow),
+				effects: []Effect{{name: "scrapTradeRow"}},
 			},
 		},
 		abilities: []*Ability{
@@ -428,11 +475,13 @@ func battlePod() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 4),
+				effects: []Effect{{name: "combat", value: 4}},
 			},
 			&Ability{
 				group:   Ally,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 2),
+				effects: []Effect{{name: "combat", value: 2}},
 			},
 		},
 	}
@@ -440,6 +489,7 @@ func battlePod() *CardEntry {
 
 func blobCarrier() *CardEntry {
 	return &CardEntry{
+		name:     "Blob Carrier",
 		cost:     6,
 		qty:      1,
 		faction:  Blob,
@@ -449,6 +499,7 @@ func blobCarrier() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 7),
+				effects: []Effect{{name: "combat", value: 7}},
 			},
 			&Ability{
 				group:      Ally,
@@ -456,6 +507,7 @@ func blobCarrier() *CardEntry {
 				id:         BlobCarrierAcquire,
 				player:     Current,
 				actions:    actionRequest(AcquireShipForFree),
+				effects:    []Effect{{name: "acquireShip"}},
 			},
 		},
 	}
@@ -463,6 +515,7 @@ func blobCarrier() *CardEntry {
 
 func blobDestroyer() *CardEntry {
 	return &CardEntry{
+		name:     "Blob Destroyer",
 		cost:     4,
 		qty:      2,
 		faction:  Blob,
@@ -472,6 +525,7 @@ func blobDestroyer() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 6),
+				effects: []Effect{{name: "combat", value: 6}},
 			},
 			&Ability{
 				group:      Ally,
@@ -479,6 +533,7 @@ func blobDestroyer() *CardEntry {
 				id:         BlobDestroyerDestroyBase,
 				player:     Current,
 				actions:    actionRequest(DestroyBaseBlobDestroyer),
+				effects:    []Effect{{name: "destroyBaseAndScrapTradeRow"}},
 			},
 		},
 	}
@@ -486,6 +541,7 @@ func blobDestroyer() *CardEntry {
 
 func imperialFighter() *CardEntry {
 	return &CardEntry{
+		name:    "Imperial Fighter",
 		cost:    1,
 		qty:     3,
 		faction: StarEmpire,
@@ -494,16 +550,19 @@ func imperialFighter() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 2),
+				effects: []Effect{{name: "combat", value: 2}},
 			},
 			&Ability{
 				group:   Primary,
 				player:  Opponent,
 				actions: changeCounter(Increase, Discard, 1),
+				effects: []Effect{{name: "discard", value: 1, opponent: true}},
 			},
 			&Ability{
 				group:   Ally,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 2),
+				effects: []Effect{{name: "combat", value: 2}},
 			},
 		},
 		cardType: Ship,
@@ -512,6 +571,7 @@ func imperialFighter() *CardEntry {
 
 func imperialFrigate() *CardEntry {
 	return &CardEntry{
+		name:    "Imperial Frigate",
 		cost:    3,
 		qty:     3,
 		faction: StarEmpire,
@@ -520,11 +580,13 @@ func imperialFrigate() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 4),
+				effects: []Effect{{name: "combat", value: 4}},
 			},
 			&Ability{
 				group:   Primary,
 				player:  Opponent,
 				actions: changeCounter(Increase, Discard, 1),
+				effects: []Effect{{name: "discard", value: 1, opponent: true}},
 			},
 			&Ability{
 				group:      Primary,
@@ -532,11 +594,13 @@ func imperialFrigate() *CardEntry {
 				id:         Utilization,
 				player:     Current,
 				actions:    drawCard,
+				effects:    []Effect{{name: "draw", value: 1}},
 			},
 			&Ability{
 				group:   Ally,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 2),
+				effects: []Effect{{name: "combat", value: 2}},
 			},
 		},
 		cardType: Ship,
@@ -545,6 +609,7 @@ func imperialFrigate() *CardEntry {
 
 func corvette() *CardEntry {
 	return &CardEntry{
+		name:    "Corvette",
 		cost:    2,
 		qty:     2,
 		faction: StarEmpire,
@@ -553,16 +618,19 @@ func corvette() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 1),
+				effects: []Effect{{name: "combat", value: 1}},
 			},
 			&Ability{
 				group:   Primary,
 				player:  Current,
 				actions: drawCard,
+				effects: []Effect{{name: "draw", value: 1}},
 			},
 			&Ability{
 				group:   Ally,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 2),
+				effects: []Effect{{name: "combat", value: 2}},
 			},
 		},
 		cardType: Ship,
@@ -571,6 +639,7 @@ func corvette() *CardEntry {
 
 func dreadnaught() *CardEntry {
 	return &CardEntry{
+		name:    "Dreadnaught",
 		cost:    7,
 		qty:     1,
 		faction: StarEmpire,
@@ -579,11 +648,13 @@ func dreadnaught() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 7),
+				effects: []Effect{{name: "combat", value: 7}},
 			},
 			&Ability{
 				group:   Primary,
 				player:  Current,
 				actions: drawCard,
+				effects: []Effect{{name: "draw", value: 1}},
 			},
 			&Ability{
 				group:      Primary,
@@ -591,6 +662,7 @@ func dreadnaught() *CardEntry {
 				id:         Utilization,
 				player:     Current,
 				actions:    changeCounter(Increase, Combat, 5),
+				effects:    []Effect{{name: "combat", value: 5}},
 			},
 		},
 		cardType: Ship,
@@ -599,6 +671,7 @@ func dreadnaught() *CardEntry {
 
 func royalRedoubt() *CardEntry {
 	return &CardEntry{
+		name:     "Royal Redoubt",
 		cost:     6,
 		qty:      1,
 		faction:  StarEmpire,
@@ -609,11 +682,13 @@ func royalRedoubt() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 3),
+				effects: []Effect{{name: "combat", value: 3}},
 			},
 			&Ability{
 				group:   Ally,
 				player:  Opponent,
 				actions: changeCounter(Increase, Discard, 1),
+				effects: []Effect{{name: "discard", value: 1, opponent: true}},
 			},
 		},
 	}
@@ -621,6 +696,7 @@ func royalRedoubt() *CardEntry {
 
 func spaceStation() *CardEntry {
 	return &CardEntry{
+		name:     "Space Station",
 		cost:     4,
 		qty:      2,
 		faction:  StarEmpire,
@@ -631,11 +707,13 @@ func spaceStation() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 2),
+				effects: []Effect{{name: "combat", value: 2}},
 			},
 			&Ability{
 				group:   Ally,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 2),
+				effects: []Effect{{name: "combat", value: 2}},
 			},
 			&Ability{
 				group:      Primary,
@@ -643,6 +721,7 @@ func spaceStation() *CardEntry {
 				id:         Utilization,
 				player:     Current,
 				actions:    changeCounter(Increase, Trade, 4),
+				effects:    []Effect{{name: "trade", value: 4}},
 			},
 		},
 	}
@@ -650,6 +729,7 @@ func spaceStation() *CardEntry {
 
 func surveyShip() *CardEntry {
 	return &CardEntry{
+		name:     "Survey Ship",
 		cost:     3,
 		qty:      3,
 		faction:  StarEmpire,
@@ -659,11 +739,13 @@ func surveyShip() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Trade, 1),
+				effects: []Effect{{name: "trade", value: 1}},
 			},
 			&Ability{
 				group:   Primary,
 				player:  Current,
 				actions: drawCard,
+				effects: []Effect{{name: "draw", value: 1}},
 			},
 			&Ability{
 				group:      Primary,
@@ -671,6 +753,7 @@ func surveyShip() *CardEntry {
 				id:         Utilization,
 				player:     Opponent,
 				actions:    changeCounter(Increase, Discard, 1),
+				effects:    []Effect{{name: "discard", value: 1, opponent: true}},
 			},
 		},
 	}
@@ -678,6 +761,7 @@ func surveyShip() *CardEntry {
 
 func warWorld() *CardEntry {
 	return &CardEntry{
+		name:     "War World",
 		cost:     5,
 		qty:      1,
 		faction:  StarEmpire,
@@ -688,11 +772,13 @@ func warWorld() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 3),
+				effects: []Effect{{name: "combat", value: 3}},
 			},
 			&Ability{
 				group:   Ally,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 4),
+				effects: []Effect{{name: "combat", value: 4}},
 			},
 		},
 	}
@@ -700,6 +786,7 @@ func warWorld() *CardEntry {
 
 func battlecruiser() *CardEntry {
 	return &CardEntry{
+		name:     "Battlecruiser",
 		cost:     6,
 		qty:      1,
 		faction:  StarEmpire,
@@ -709,28 +796,30 @@ func battlecruiser() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 5),
+				effects: []Effect{{name: "combat", value: 5}},
 			},
 			&Ability{
 				group:   Primary,
 				player:  Current,
 				actions: drawCard,
+				effects: []Effect{{name: "draw", value: 1}},
 			},
 			&Ability{
 				group:      Primary,
 				actionType: Activated,
 				id:         Utilization,
 				player:     Current,
-				actions: func(player PlayerId, cardId string, state *State) []StateAction {
+				actions: func(player PlayerId, cardId string, state *State, logger *slog.Logger) []StateAction {
 					currentDeck, err := locationByPointer(CurrentDeck, player)
 					if err != nil {
 						// TODO: handle exception
-						log.Println(err)
+						logger.Warn("ability not applied", "err", err)
 						return []StateAction{}
 					}
 					currentHand, err := locationByPointer(CurrentHand, player)
 					if err != nil {
 						// TODO: handle exception
-						log.Println(err)
+						logger.Warn("ability not applied", "err", err)
 						return []StateAction{}
 					}
 					return []StateAction{
@@ -745,11 +834,13 @@ func battlecruiser() *CardEntry {
 						},
 					}
 				},
+				effects: []Effect{{name: "draw", value: 1}, {name: "destroyBase"}},
 			},
 			&Ability{
 				group:   Ally,
 				player:  Opponent,
 				actions: changeCounter(Increase, Discard, 1),
+				effects: []Effect{{name: "discard", value: 1, opponent: true}},
 			},
 		},
 	}
@@ -757,6 +848,7 @@ func battlecruiser() *CardEntry {
 
 func battleMech() *CardEntry {
 	return &CardEntry{
+		name:     "Battle Mech",
 		cost:     5,
 		qty:      1,
 		faction:  MachineCult,
@@ -766,6 +858,7 @@ func battleMech() *CardEntry {
 				group:   BeforePlay,
 				player:  Current,
 				actions: actionRequest(ScrapCard),
+				effects: []Effect{{name: "scrapHandOrDiscard"}},
 			},
 		},
 		abilities: []*Ability{
@@ -773,11 +866,13 @@ func battleMech() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 4),
+				effects: []Effect{{name: "combat", value: 4}},
 			},
 			&Ability{
 				group:   Ally,
 				player:  Current,
 				actions: drawCard,
+				effects: []Effect{{name: "draw", value: 1}},
 			},
 		},
 	}
@@ -785,6 +880,7 @@ func battleMech() *CardEntry {
 
 func missileBot() *CardEntry {
 	return &CardEntry{
+		name:     "Missile Bot",
 		cost:     2,
 		qty:      3,
 		faction:  MachineCult,
@@ -794,6 +890,7 @@ func missileBot() *CardEntry {
 				group:   BeforePlay,
 				player:  Current,
 				actions: actionRequest(ScrapCard),
+				effects: []Effect{{name: "scrapHandOrDiscard"}},
 			},
 		},
 		abilities: []*Ability{
@@ -801,11 +898,13 @@ func missileBot() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 2),
+				effects: []Effect{{name: "combat", value: 2}},
 			},
 			&Ability{
 				group:   Ally,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 2),
+				effects: []Effect{{name: "combat", value: 2}},
 			},
 		},
 	}
@@ -813,6 +912,7 @@ func missileBot() *CardEntry {
 
 func supplyBot() *CardEntry {
 	return &CardEntry{
+		name:     "Supply Bot",
 		cost:     3,
 		qty:      3,
 		faction:  MachineCult,
@@ -822,6 +922,7 @@ func supplyBot() *CardEntry {
 				group:   BeforePlay,
 				player:  Current,
 				actions: actionRequest(ScrapCard),
+				effects: []Effect{{name: "scrapHandOrDiscard"}},
 			},
 		},
 		abilities: []*Ability{
@@ -829,11 +930,13 @@ func supplyBot() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Trade, 2),
+				effects: []Effect{{name: "trade", value: 2}},
 			},
 			&Ability{
 				group:   Ally,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 2),
+				effects: []Effect{{name: "combat", value: 2}},
 			},
 		},
 	}
@@ -841,6 +944,7 @@ func supplyBot() *CardEntry {
 
 func tradeBot() *CardEntry {
 	return &CardEntry{
+		name:     "Trade Bot",
 		cost:     1,
 		qty:      3,
 		faction:  MachineCult,
@@ -850,6 +954,7 @@ func tradeBot() *CardEntry {
 				group:   BeforePlay,
 				player:  Current,
 				actions: actionRequest(ScrapCard),
+				effects: []Effect{{name: "scrapHandOrDiscard"}},
 			},
 		},
 		abilities: []*Ability{
@@ -857,11 +962,13 @@ func tradeBot() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Trade, 1),
+				effects: []Effect{{name: "trade", value: 1}},
 			},
 			&Ability{
 				group:   Ally,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 2),
+				effects: []Effect{{name: "combat", value: 2}},
 			},
 		},
 	}
@@ -869,6 +976,7 @@ func tradeBot() *CardEntry {
 
 func missileMech() *CardEntry {
 	return &CardEntry{
+		name:     "Missile Mech",
 		cost:     6,
 		qty:      1,
 		faction:  MachineCult,
@@ -878,6 +986,7 @@ func missileMech() *CardEntry {
 				group:   BeforePlay,
 				player:  Current,
 				actions: actionRequest(DestroyBaseForFree),
+				effects: []Effect{{name: "destroyBase"}},
 			},
 		},
 		abilities: []*Ability{
@@ -885,11 +994,13 @@ func missileMech() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 6),
+				effects: []Effect{{name: "combat", value: 6}},
 			},
 			&Ability{
 				group:   Ally,
 				player:  Current,
 				actions: drawCard,
+				effects: []Effect{{name: "draw", value: 1}},
 			},
 		},
 	}
@@ -897,6 +1008,7 @@ func missileMech() *CardEntry {
 
 func patrolMech() *CardEntry {
 	return &CardEntry{
+		name:     "Patrol Mech",
 		cost:     4,
 		qty:      2,
 		faction:  MachineCult,
@@ -906,13 +1018,14 @@ func patrolMech() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: actionRequest(ActivateAbility),
+				effects: []Effect{{name: "chooseOne"}},
 			},
 			&Ability{
 				group:      Primary,
 				actionType: Activated,
 				id:         PatrolMechTrade,
 				player:     Current,
-				actions: func(player PlayerId, cardId string, state *State) []StateAction {
+				actions: func(player PlayerId, cardId string, state *State, logger *slog.Logger) []StateAction {
 					return []StateAction{
 						&StateActionChangeCounterValue{
 							player:    player,
@@ -930,13 +1043,15 @@ func patrolMech() *CardEntry {
 						},
 					}
 				},
+				effects:      []Effect{{name: "trade", value: 3}},
+				alternatives: []AbilityId{PatrolMechCombat},
 			},
 			&Ability{
 				group:      Primary,
 				actionType: Activated,
 				id:         PatrolMechCombat,
 				player:     Current,
-				actions: func(player PlayerId, cardId string, state *State) []StateAction {
+				actions: func(player PlayerId, cardId string, state *State, logger *slog.Logger) []StateAction {
 					return []StateAction{
 						&StateActionChangeCounterValue{
 							player:    player,
@@ -954,6 +1069,8 @@ func patrolMech() *CardEntry {
 						},
 					}
 				},
+				effects:      []Effect{{name: "combat", value: 5}},
+				alternatives: []AbilityId{PatrolMechTrade},
 			},
 			&Ability{
 				group:      Ally,
@@ -961,6 +1078,7 @@ func patrolMech() *CardEntry {
 				id:         PatrolMechScrap,
 				player:     Current,
 				actions:    actionRequest(ScrapCard),
+				effects:    []Effect{{name: "scrapHandOrDiscard"}},
 			},
 		},
 	}
@@ -968,6 +1086,7 @@ func patrolMech() *CardEntry {
 
 func federationShuttle() *CardEntry {
 	return &CardEntry{
+		name:     "Federation Shuttle",
 		cost:     1,
 		qty:      3,
 		faction:  TradeFederation,
@@ -977,11 +1096,13 @@ func federationShuttle() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Trade, 2),
+				effects: []Effect{{name: "trade", value: 2}},
 			},
 			&Ability{
 				group:   Ally,
 				player:  Current,
 				actions: changeCounter(Increase, Authority, 4),
+				effects: []Effect{{name: "authority", value: 4}},
 			},
 		},
 	}
@@ -989,6 +1110,7 @@ func federationShuttle() *CardEntry {
 
 func cutter() *CardEntry {
 	return &CardEntry{
+		name:     "Cutter",
 		cost:     2,
 		qty:      3,
 		faction:  TradeFederation,
@@ -998,16 +1120,19 @@ func cutter() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Trade, 2),
+				effects: []Effect{{name: "trade", value: 2}},
 			},
 			&Ability{
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Authority, 4),
+				effects: []Effect{{name: "authority", value: 4}},
 			},
 			&Ability{
 				group:   Ally,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 4),
+				effects: []Effect{{name: "combat", value: 4}},
 			},
 		},
 	}
@@ -1015,6 +1140,7 @@ func cutter() *CardEntry {
 
 func tradeEscort() *CardEntry {
 	return &CardEntry{
+		name:     "Trade Escort",
 		cost:     5,
 		qty:      1,
 		faction:  TradeFederation,
@@ -1024,16 +1150,19 @@ func tradeEscort() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 4),
+				effects: []Effect{{name: "combat", value: 4}},
 			},
 			&Ability{
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Authority, 4),
+				effects: []Effect{{name: "authority", value: 4}},
 			},
 			&Ability{
 				group:   Ally,
 				player:  Current,
 				actions: drawCard,
+				effects: []Effect{{name: "draw", value: 1}},
 			},
 		},
 	}
@@ -1041,6 +1170,7 @@ func tradeEscort() *CardEntry {
 
 func flagship() *CardEntry {
 	return &CardEntry{
+		name:     "Flagship",
 		cost:     6,
 		qty:      1,
 		faction:  TradeFederation,
@@ -1050,16 +1180,19 @@ func flagship() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 5),
+				effects: []Effect{{name: "combat", value: 5}},
 			},
 			&Ability{
 				group:   Primary,
 				player:  Current,
 				actions: drawCard,
+				effects: []Effect{{name: "draw", value: 1}},
 			},
 			&Ability{
 				group:   Ally,
 				player:  Current,
 				actions: changeCounter(Increase, Authority, 5),
+				effects: []Effect{{name: "authority", value: 5}},
 			},
 		},
 	}
@@ -1067,6 +1200,7 @@ func flagship() *CardEntry {
 
 func commandShip() *CardEntry {
 	return &CardEntry{
+		name:     "Command Ship",
 		cost:     8,
 		qty:      1,
 		faction:  TradeFederation,
@@ -1076,21 +1210,25 @@ func commandShip() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 5),
+				effects: []Effect{{name: "combat", value: 5}},
 			},
 			&Ability{
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Authority, 4),
+				effects: []Effect{{name: "authority", value: 4}},
 			},
 			&Ability{
 				group:   Primary,
 				player:  Current,
 				actions: drawCard,
+				effects: []Effect{{name: "draw", value: 1}},
 			},
 			&Ability{
 				group:   Primary,
 				player:  Current,
 				actions: drawCard,
+				effects: []Effect{{name: "draw", value: 1}},
 			},
 			&Ability{
 				group:      Ally,
@@ -1098,6 +1236,7 @@ func commandShip() *CardEntry {
 				id:         CommandShipDestroyBase,
 				player:     Current,
 				actions:    actionRequest(DestroyBaseForFree),
+				effects:    []Effect{{name: "destroyBase"}},
 			},
 		},
 	}
@@ -1105,6 +1244,7 @@ func commandShip() *CardEntry {
 
 func tradingPost() *CardEntry {
 	return &CardEntry{
+		name:     "Trading Post",
 		cost:     3,
 		qty:      2,
 		faction:  TradeFederation,
@@ -1116,7 +1256,7 @@ func tradingPost() *CardEntry {
 				actionType: Activated,
 				id:         TradingPostAuthority,
 				player:     Current,
-				actions: func(player PlayerId, cardId string, state *State) []StateAction {
+				actions: func(player PlayerId, cardId string, state *State, logger *slog.Logger) []StateAction {
 					return []StateAction{
 						&StateActionChangeCounterValue{
 							player:    player,
@@ -1130,13 +1270,15 @@ func tradingPost() *CardEntry {
 						},
 					}
 				},
+				effects:      []Effect{{name: "authority", value: 1}},
+				alternatives: []AbilityId{TradingPostTrade},
 			},
 			&Ability{
 				group:      Primary,
 				actionType: Activated,
 				id:         TradingPostTrade,
 				player:     Current,
-				actions: func(player PlayerId, cardId string, state *State) []StateAction {
+				actions: func(player PlayerId, cardId string, state *State, logger *slog.Logger) []StateAction {
 					return []StateAction{
 						&StateActionChangeCounterValue{
 							player:    player,
@@ -1150,6 +1292,8 @@ func tradingPost() *CardEntry {
 						},
 					}
 				},
+				effects:      []Effect{{name: "trade", value: 1}},
+				alternatives: []AbilityId{TradingPostAuthority},
 			},
 			&Ability{
 				group:      Primary,
@@ -1157,6 +1301,7 @@ func tradingPost() *CardEntry {
 				id:         Utilization,
 				player:     Current,
 				actions:    changeCounter(Increase, Combat, 3),
+				effects:    []Effect{{name: "combat", value: 3}},
 			},
 		},
 	}
@@ -1164,6 +1309,7 @@ func tradingPost() *CardEntry {
 
 func barterWorld() *CardEntry {
 	return &CardEntry{
+		name:     "Barter World",
 		cost:     4,
 		qty:      2,
 		faction:  TradeFederation,
@@ -1175,7 +1321,7 @@ func barterWorld() *CardEntry {
 				actionType: Activated,
 				id:         BarterWorldAuthority,
 				player:     Current,
-				actions: func(player PlayerId, cardId string, state *State) []StateAction {
+				actions: func(player PlayerId, cardId string, state *State, logger *slog.Logger) []StateAction {
 					return []StateAction{
 						&StateActionChangeCounterValue{
 							player:    player,
@@ -1189,13 +1335,15 @@ func barterWorld() *CardEntry {
 						},
 					}
 				},
+				effects:      []Effect{{name: "authority", value: 2}},
+				alternatives: []AbilityId{BarterWorldTrade},
 			},
 			&Ability{
 				group:      Primary,
 				actionType: Activated,
 				id:         BarterWorldTrade,
 				player:     Current,
-				actions: func(player PlayerId, cardId string, state *State) []StateAction {
+				actions: func(player PlayerId, cardId string, state *State, logger *slog.Logger) []StateAction {
 					return []StateAction{
 						&StateActionChangeCounterValue{
 							player:    player,
@@ -1209,6 +1357,8 @@ func barterWorld() *CardEntry {
 						},
 					}
 				},
+				effects:      []Effect{{name: "trade", value: 2}},
+				alternatives: []AbilityId{BarterWorldAuthority},
 			},
 			&Ability{
 				group:      Primary,
@@ -1216,6 +1366,7 @@ func barterWorld() *CardEntry {
 				id:         Utilization,
 				player:     Current,
 				actions:    changeCounter(Increase, Combat, 5),
+				effects:    []Effect{{name: "combat", value: 5}},
 			},
 		},
 	}
@@ -1223,6 +1374,7 @@ func barterWorld() *CardEntry {
 
 func defenseCenter() *CardEntry {
 	return &CardEntry{
+		name:     "Defense Center",
 		cost:     5,
 		qty:      1,
 		faction:  TradeFederation,
@@ -1234,7 +1386,7 @@ func defenseCenter() *CardEntry {
 				actionType: Activated,
 				id:         DefenseCenterAuthority,
 				player:     Current,
-				actions: func(player PlayerId, cardId string, state *State) []StateAction {
+				actions: func(player PlayerId, cardId string, state *State, logger *slog.Logger) []StateAction {
 					return []StateAction{
 						&StateActionChangeCounterValue{
 							player:    player,
@@ -1248,13 +1400,15 @@ func defenseCenter() *CardEntry {
 						},
 					}
 				},
+				effects:      []Effect{{name: "authority", value: 3}},
+				alternatives: []AbilityId{DefenseCenterCombat},
 			},
 			&Ability{
 				group:      Primary,
 				actionType: Activated,
 				id:         DefenseCenterCombat,
 				player:     Current,
-				actions: func(player PlayerId, cardId string, state *State) []StateAction {
+				actions: func(player PlayerId, cardId string, state *State, logger *slog.Logger) []StateAction {
 					return []StateAction{
 						&StateActionChangeCounterValue{
 							player:    player,
@@ -1268,11 +1422,14 @@ func defenseCenter() *CardEntry {
 						},
 					}
 				},
+				effects:      []Effect{{name: "combat", value: 2}},
+				alternatives: []AbilityId{DefenseCenterAuthority},
 			},
 			&Ability{
 				group:   Ally,
 				player:  Current,
 				actions: changeCounter(Increase, Combat, 2),
+				effects: []Effect{{name: "combat", value: 2}},
 			},
 		},
 	}
@@ -1280,6 +1437,7 @@ func defenseCenter() *CardEntry {
 
 func portOfCall() *CardEntry {
 	return &CardEntry{
+		name:     "Port of Call",
 		cost:     6,
 		qty:      1,
 		faction:  TradeFederation,
@@ -1290,23 +1448,24 @@ func portOfCall() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Trade, 3),
+				effects: []Effect{{name: "trade", value: 3}},
 			},
 			&Ability{
 				group:      Primary,
 				actionType: Activated,
 				id:         Utilization,
 				player:     Current,
-				actions: func(player PlayerId, cardId string, state *State) []StateAction {
+				actions: func(player PlayerId, cardId string, state *State, logger *slog.Logger) []StateAction {
 					currentDeck, err := locationByPointer(CurrentDeck, player)
 					if err != nil {
 						// TODO: handle exception
-						log.Println(err)
+						logger.Warn("ability not applied", "err", err)
 						return []StateAction{}
 					}
 					currentHand, err := locationByPointer(CurrentHand, player)
 					if err != nil {
 						// TODO: handle exception
-						log.Println(err)
+						logger.Warn("ability not applied", "err", err)
 						return []StateAction{}
 					}
 					return []StateAction{
@@ -1321,6 +1480,7 @@ func portOfCall() *CardEntry {
 						},
 					}
 				},
+				effects: []Effect{{name: "draw", value: 1}, {name: "destroyBase"}},
 			},
 		},
 	}
@@ -1328,6 +1488,7 @@ func portOfCall() *CardEntry {
 
 func freighter() *CardEntry {
 	return &CardEntry{
+		name:     "Freighter",
 		cost:     4,
 		qty:      2,
 		faction:  TradeFederation,
@@ -1337,11 +1498,13 @@ func freighter() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Trade, 4),
+				effects: []Effect{{name: "trade", value: 4}},
 			},
 			&Ability{
 				group:   Ally,
 				player:  Current,
 				actions: changeCounter(Increase, ShipsOnTop, 1),
+				effects: []Effect{{name: "ships on top", value: 1}},
 			},
 		},
 	}
@@ -1349,6 +1512,7 @@ func freighter() *CardEntry {
 
 func centralOffice() *CardEntry {
 	return &CardEntry{
+		name:     "Central Office",
 		cost:     7,
 		qty:      1,
 		faction:  TradeFederation,
@@ -1359,16 +1523,19 @@ func centralOffice() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Trade, 2),
+				effects: []Effect{{name: "trade", value: 2}},
 			},
 			&Ability{
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, ShipsOnTop, 1),
+				effects: []Effect{{name: "ships on top", value: 1}},
 			},
 			&Ability{
 				group:   Ally,
 				player:  Current,
 				actions: drawCard,
+				effects: []Effect{{name: "draw", value: 1}},
 			},
 		},
 	}
@@ -1376,6 +1543,7 @@ func centralOffice() *CardEntry {
 
 func junkyard() *CardEntry {
 	return &CardEntry{
+		name:     "Junkyard",
 		cost:     6,
 		qty:      1,
 		faction:  MachineCult,
@@ -1388,6 +1556,7 @@ func junkyard() *CardEntry {
 				id:         Junkyard,
 				player:     Current,
 				actions:    actionRequest(ScrapCard),
+				effects:    []Effect{{name: "scrapHandOrDiscard"}},
 			},
 		},
 	}
@@ -1395,6 +1564,7 @@ func junkyard() *CardEntry {
 
 func embassyYacht() *CardEntry {
 	return &CardEntry{
+		name:     "Embassy Yacht",
 		cost:     3,
 		qty:      2,
 		faction:  TradeFederation,
@@ -1404,41 +1574,37 @@ func embassyYacht() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Trade, 2),
+				effects: []Effect{{name: "trade", value: 2}},
 			},
 			&Ability{
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Increase, Authority, 3),
+				effects: []Effect{{name: "authority", value: 3}},
 			},
 			&Ability{
 				group:  Primary,
 				player: Current,
-				actions: func(player PlayerId, cardId string, state *State) []StateAction {
+				actions: func(player PlayerId, cardId string, state *State, logger *slog.Logger) []StateAction {
 					currentBases, err := locationByPointer(CurrentBases, player)
 					if err != nil {
 						// TODO: handle exception
-						log.Println(err)
+						logger.Warn("ability not applied", "err", err)
 						return []StateAction{}
 					}
-					basesCount := 0
-					for _, card := range state.Cards {
-						if card.Location == currentBases {
-							basesCount += 1
-						}
-					}
-					if basesCount < 2 {
+					if len(state.pile(currentBases)) < 2 {
 						return []StateAction{}
 					}
 					currentDeck, err := locationByPointer(CurrentDeck, player)
 					if err != nil {
 						// TODO: handle exception
-						log.Println(err)
+						logger.Warn("ability not applied", "err", err)
 						return []StateAction{}
 					}
 					currentHand, err := locationByPointer(CurrentHand, player)
 					if err != nil {
 						// TODO: handle exception
-						log.Println(err)
+						logger.Warn("ability not applied", "err", err)
 						return []StateAction{}
 					}
 					return []StateAction{
@@ -1452,6 +1618,7 @@ func embassyYacht() *CardEntry {
 						},
 					}
 				},
+				effects: []Effect{{name: "drawWithBases", value: 2}},
 			},
 		},
 	}
@@ -1459,6 +1626,7 @@ func embassyYacht() *CardEntry {
 
 func machineBase() *CardEntry {
 	return &CardEntry{
+		name:     "Machine Base",
 		cost:     7,
 		qty:      1,
 		faction:  MachineCult,
@@ -1470,17 +1638,17 @@ func machineBase() *CardEntry {
 				actionType: Activated,
 				id:         MachineBase,
 				player:     Current,
-				actions: func(player PlayerId, cardId string, state *State) []StateAction {
+				actions: func(player PlayerId, cardId string, state *State, logger *slog.Logger) []StateAction {
 					currentDeck, err := locationByPointer(CurrentDeck, player)
 					if err != nil {
 						// TODO: handle exception
-						log.Println(err)
+						logger.Warn("ability not applied", "err", err)
 						return []StateAction{}
 					}
 					currentHand, err := locationByPointer(CurrentHand, player)
 					if err != nil {
 						// TODO: handle exception
-						log.Println(err)
+						logger.Warn("ability not applied", "err", err)
 						return []StateAction{}
 					}
 					return []StateAction{
@@ -1495,6 +1663,7 @@ func machineBase() *CardEntry {
 						},
 					}
 				},
+				effects: []Effect{{name: "draw", value: 1}, {name: "scrapHand"}},
 			},
 		},
 	}
@@ -1502,6 +1671,7 @@ func machineBase() *CardEntry {
 
 func brainWorld() *CardEntry {
 	return &CardEntry{
+		name:     "Brain World",
 		cost:     8,
 		qty:      1,
 		faction:  MachineCult,
@@ -1514,6 +1684,7 @@ func brainWorld() *CardEntry {
 				id:         BrainWorld,
 				player:     Current,
 				actions:    actionRequest(ActivateBrainWorld),
+				effects:    []Effect{{name: "scrapAndDraw"}},
 			},
 		},
 	}
@@ -1521,6 +1692,7 @@ func brainWorld() *CardEntry {
 
 func mechWorld() *CardEntry {
 	return &CardEntry{
+		name:     "Mech World",
 		cost:     5,
 		qty:      1,
 		faction:  MachineCult,
@@ -1531,6 +1703,7 @@ func mechWorld() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: actionRequest(ActivateMechWorld),
+				effects: []Effect{{name: "allyForAllFactions"}},
 			},
 		},
 	}
@@ -1538,6 +1711,7 @@ func mechWorld() *CardEntry {
 
 func recyclingStation() *CardEntry {
 	return &CardEntry{
+		name:     "Recycling Station",
 		cost:     4,
 		qty:      2,
 		faction:  StarEmpire,
@@ -1550,6 +1724,7 @@ func recyclingStation() *CardEntry {
 				id:         RecyclingStation,
 				player:     Current,
 				actions:    actionRequest(ActivateRecyclingStation),
+				effects:    []Effect{{name: "discardAndDraw"}},
 			},
 		},
 	}
@@ -1557,6 +1732,7 @@ func recyclingStation() *CardEntry {
 
 func fleetHQ() *CardEntry {
 	return &CardEntry{
+		name:     "Fleet HQ",
 		cost:     8,
 		qty:      1,
 		faction:  StarEmpire,
@@ -1567,6 +1743,7 @@ func fleetHQ() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: changeCounter(Set, fleetFlag, 1),
+				effects: []Effect{{name: "shipCombat", value: 1}},
 			},
 		},
 	}
@@ -1574,6 +1751,7 @@ func fleetHQ() *CardEntry {
 
 func blobWorld() *CardEntry {
 	return &CardEntry{
+		name:     "Blob World",
 		cost:     8,
 		qty:      1,
 		faction:  Blob,
@@ -1585,7 +1763,7 @@ func blobWorld() *CardEntry {
 				actionType: Activated,
 				id:         BlobWorldCombat,
 				player:     Current,
-				actions: func(player PlayerId, cardId string, state *State) []StateAction {
+				actions: func(player PlayerId, cardId string, state *State, logger *slog.Logger) []StateAction {
 					return []StateAction{
 						&StateActionChangeCounterValue{
 							player:    player,
@@ -1599,31 +1777,33 @@ func blobWorld() *CardEntry {
 						},
 					}
 				},
+				effects:      []Effect{{name: "combat", value: 5}},
+				alternatives: []AbilityId{BlobWorldDraw},
 			},
 			&Ability{
 				group:      Primary,
 				actionType: Activated,
 				id:         BlobWorldDraw,
 				player:     Current,
-				actions: func(player PlayerId, cardId string, state *State) []StateAction {
+				actions: func(player PlayerId, cardId string, state *State, logger *slog.Logger) []StateAction {
 					actions := []StateAction{}
 					counters, err := countersByPointer(player, CurrentPlayerCounters, state)
 					if err != nil {
 						// TODO handle error
-						log.Println(err)
+						logger.Warn("ability not applied", "err", err)
 						return actions
 					}
 
 					currentDeck, err := locationByPointer(CurrentDeck, player)
 					if err != nil {
 						// TODO: handle exception
-						log.Println(err)
+						logger.Warn("ability not applied", "err", err)
 						return []StateAction{}
 					}
 					currentHand, err := locationByPointer(CurrentHand, player)
 					if err != nil {
 						// TODO: handle exception
-						log.Println(err)
+						logger.Warn("ability not applied", "err", err)
 						return []StateAction{}
 					}
 
@@ -1639,6 +1819,7 @@ func blobWorld() *CardEntry {
 
 					return actions
 				},
+				effects: []Effect{{name: "drawPerBlob"}},
 			},
 		},
 	}
@@ -1646,6 +1827,7 @@ func blobWorld() *CardEntry {
 
 func stealthNeedle() *CardEntry {
 	return &CardEntry{
+		name:     "Stealth Needle",
 		cost:     4,
 		qty:      1,
 		faction:  MachineCult,
@@ -1655,6 +1837,7 @@ func stealthNeedle() *CardEntry {
 				group:   Primary,
 				player:  Current,
 				actions: actionRequest(ActivateNeedle),
+				effects: []Effect{{name: "copyShip"}},
 			},
 		},
 	}
@@ -1662,6 +1845,7 @@ func stealthNeedle() *CardEntry {
 
 func battleStation() *CardEntry {
 	return &CardEntry{
+		name:     "Battle Station",
 		cost:     3,
 		qty:      2,
 		faction:  MachineCult,
@@ -1674,6 +1858,7 @@ func battleStation() *CardEntry {
 				id:         Utilization,
 				player:     Current,
 				actions:    changeCounter(Increase, Combat, 5),
+				effects:    []Effect{{name: "combat", value: 5}},
 			},
 		},
 	}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// fuzzGame is a dealt two player game whose actions are applied in place of
+// the state manager goroutine
+type fuzzGame struct {
+	stateManager *StateManager
+	middleware   *Middleware
+}
+
+func newFuzzGame(t *testing.T) *fuzzGame {
+	options := GameOptions{Players: MinPlayers, Seed: 1}
+	err := resolveSetup(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deck := gameDeck(options)
+	game := &fuzzGame{newStateManager(deck, options), newMiddleware(deck, options)}
+	game.apply(t, "prepare", game.middleware.prepareState())
+	return game
+}
+
+// apply steps the actions which change the state, the replies of the state
+// manager aren't read here
+func (g *fuzzGame) apply(t *testing.T, message string, actions []StateAction) {
+	for _, action := range actions {
+		if action.Type() == GetState || action.Type() == GetVersion {
+			continue
+		}
+		err := g.stateManager.step(action)
+		if err != nil {
+			t.Fatalf("%s: %v", message, err)
+		}
+	}
+	for _, violation := range g.stateManager.state.violations(g.stateManager.totalCards) {
+		t.Errorf("%s: %s", message, violation)
+	}
+}
+
+// handle applies the message, the refused moves change nothing
+func (g *fuzzGame) handle(t *testing.T, player PlayerId, message string) error {
+	actions, err := g.middleware.safeHandle(message, player, g.stateManager.state)
+	var wrongMove *WrongMoveError
+	if errors.As(err, &wrongMove) {
+		return err
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.apply(t, message, actions)
+	return nil
+}
+
+// Any message from any seat is either refused or leaves a consistent state
+func FuzzMessages(f *testing.F) {
+	seeds := []string{
+		"1,scout_1", "1,scout_99", "1,explorer_1", "2", "3,5", "3,-5", "3,1,7",
+		"4,cutter_1", "4,explorer_1", "4,nothing", "6", "7,tradingPost_1",
+		"8,scout_1", "9,explorer_1,1", "9,scout_1,x", "10", "10,viper_1",
+		"11,cutter_1", "12,scout_1", "13,barterWorld_1", "14,cutter_1",
+		"15,spaceStation_1", "16,scout_1,viper_1", "17", "18,scout_1",
+		"19,scout_1", "20", "21", "", ",", "1,", "99,scout_1",
+	}
+	for _, seed := range seeds {
+		f.Add(uint8(FirstPlayer), seed, "2")
+	}
+	f.Fuzz(func(t *testing.T, player uint8, first string, second string) {
+		game := newFuzzGame(t)
+		game.handle(t, PlayerId(player), first)
+		game.handle(t, PlayerId(player), second)
+		game.handle(t, PlayerId(player), first)
+	})
+}
+
+// Following the rules never breaks the state, every byte of the input picks
+// one of the legal moves
+func FuzzLegalMoves(f *testing.F) {
+	f.Add([]byte{0, 0, 0, 0, 0, 1, 2, 3, 255})
+	f.Add([]byte("the quick brown fox jumps over the lazy dog"))
+	f.Add(make([]byte, 200))
+	f.Fuzz(func(t *testing.T, choices []byte) {
+		game := newFuzzGame(t)
+		for _, choice := range choices {
+			state := game.stateManager.state
+			if state.Winner != 0 {
+				return
+			}
+			moves := legalMoves(state, *game.middleware.deck)
+			move := moves[int(choice)%len(moves)]
+			err := game.handle(t, state.Turn, move)
+			// Undo is listed even when there is nothing to undo
+			if err != nil && move != fmt.Sprint(int(Undo)) {
+				t.Fatalf("legal move %s: %v", move, err)
+			}
+		}
+	})
+}
+
+// legalMoves lists the messages the player of the turn may send, the
+// answers to the requested action go first
+func legalMoves(state *State, deck map[string]*CardEntry) []string {
+	player := state.Turn
+	locations := seatLocations[player]
+	counters := state.counters(player)
+	hand := state.pile(locations.Hand)
+	opponents := []PlayerId{}
+	for i := 1; i <= state.Players; i++ {
+		if canAttack(state, player, PlayerId(i)) {
+			opponents = append(opponents, PlayerId(i))
+		}
+	}
+	bases := []string{}
+	for _, opponent := range opponents {
+		bases = append(bases, state.pile(seatLocations[opponent].Bases)...)
+	}
+	answers := func(action UserAction, ids ...string) []string {
+		moves := []string{}
+		for _, id := range ids {
+			moves = append(moves, fmt.Sprintf("%d,%s", action, id))
+		}
+		return moves
+	}
+
+	var moves []string
+	switch state.actionRequest(player).Action {
+	case DiscardCard:
+		moves = answers(DiscardCard, hand...)
+	case Start:
+		moves = []string{fmt.Sprint(int(Start))}
+	case ScrapCard:
+		moves = append(answers(ScrapCard, append(hand, state.pile(locations.Discard)...)...), fmt.Sprint(int(ScrapCard)))
+	case ScrapCardTradeRow:
+		moves = append(answers(ScrapCardTradeRow, state.pile(TradeRow)...), fmt.Sprint(int(ScrapCardTradeRow)))
+	case ScrapCardInHand:
+		moves = answers(ScrapCardInHand, hand...)
+	case DestroyBaseForFree:
+		moves = append(answers(DestroyBaseForFree, bases...), fmt.Sprint(int(DestroyBaseForFree)))
+	case DestroyBaseBlobDestroyer:
+		moves = append(answers(DestroyBaseBlobDestroyer, bases...), fmt.Sprint(int(DestroyBaseBlobDestroyer)))
+	case AcquireShipForFree:
+		for _, id := range state.pile(TradeRow) {
+			if deck[strings.Split(id, "_")[0]].cardType == Ship {
+				moves = append(moves, fmt.Sprintf("%d,%s", AcquireShipForFree, id))
+			}
+		}
+	case ActivateBrainWorld:
+		moves = append(answers(ActivateBrainWorld, append(hand, state.pile(locations.Discard)...)...), fmt.Sprint(int(ActivateBrainWorld)))
+	case ActivateRecyclingStation:
+		moves = append(answers(ActivateRecyclingStation, hand...), fmt.Sprint(int(ActivateRecyclingStation)))
+	case ActivateMechWorld:
+		moves = []string{fmt.Sprint(int(ActivateMechWorld))}
+	case ActivateNeedle:
+		for _, id := range state.pile(locations.Table) {
+			if id != NEEDLE_ID && deck[strings.Split(id, "_")[0]].cardType == Ship {
+				moves = append(moves, fmt.Sprintf("%d,%s", ActivateNeedle, id))
+			}
+		}
+	}
+	if len(moves) > 0 {
+		return moves
+	}
+
+	moves = append(answers(Play, hand...), fmt.Sprint(int(End)), fmt.Sprint(int(Undo)))
+	inPlay := make(map[string]bool)
+	for _, id := range append(state.pile(locations.Table), state.pile(locations.Bases)...) {
+		inPlay[id] = true
+	}
+	for _, id := range sortedAbilityCards(state) {
+		if !inPlay[strings.TrimSuffix(id, NEEDLE_SUFFIX)] {
+			continue
+		}
+		for ability := AbilityId(0); ability <= BlobWorldDraw; ability++ {
+			if state.ActivatedAbilities[id][ability] {
+				moves = append(moves, fmt.Sprintf("%d,%s,%d", ActivateAbility, id, ability))
+			}
+		}
+	}
+	market := state.pile(TradeRow)
+	if explorer, ok := state.pile(Explorers).top(); ok {
+		market = append(market, explorer)
+	}
+	for _, id := range market {
+		if deck[strings.Split(id, "_")[0]].cost <= counters.Trade {
+			moves = append(moves, fmt.Sprintf("%d,%s", Buy, id))
+		}
+	}
+	for _, id := range bases {
+		if deck[strings.Split(id, "_")[0]].defense <= counters.Combat {
+			moves = append(moves, fmt.Sprintf("%d,%s", DestroyBase, id))
+		}
+	}
+	if counters.Combat > 0 {
+		for _, opponent := range opponents {
+			moves = append(moves, fmt.Sprintf("%d,%d,%d", Damage, counters.Combat, opponent))
+		}
+	}
+	return moves
+}
+
+// sortedAbilityCards keeps the moves of the same input the same
+func sortedAbilityCards(state *State) []string {
+	ids := []string{}
+	for id := range state.ActivatedAbilities {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
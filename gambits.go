@@ -0,0 +1,215 @@
+package main
+
+import "log/slog"
+
+const GambitsQty int = 2
+
+func getGambits() *map[string]*CardEntry {
+	gambits := make(map[string]*CardEntry)
+
+	gambits["boldRaid"] = boldRaid()
+	gambits["energyShield"] = energyShield()
+	gambits["frontierFleet"] = frontierFleet()
+	gambits["politicalManeuver"] = politicalManeuver()
+	gambits["riseToPower"] = riseToPower()
+	gambits["salvageOperation"] = salvageOperation()
+	gambits["smugglingRun"] = smugglingRun()
+	gambits["surpriseAssault"] = surpriseAssault()
+	gambits["unlikelyAlliance"] = unlikelyAlliance()
+
+	return &gambits
+}
+
+func addGambits(deck *map[string]*CardEntry) {
+	for key, gambit := range *getGambits() {
+		(*deck)[key] = gambit
+	}
+}
+
+func boldRaid() *CardEntry {
+	return &CardEntry{
+		name:     "Bold Raid",
+		qty:      1,
+		faction:  Unaligned,
+		cardType: Gambit,
+		abilities: []*Ability{
+			&Ability{
+				group:      Primary,
+				actionType: Activated,
+				id:         Utilization,
+				player:     Current,
+				actions: func(player PlayerId, cardId string, state *State, logger *slog.Logger) []StateAction {
+					actions := drawCard(player, cardId, state, logger)
+					actions = append(
+						actions,
+						&StateActionRequestUserAction{
+							player: player,
+							action: DestroyBaseForFree,
+							cardId: cardId,
+						},
+					)
+					return actions
+				},
+				effects: []Effect{{name: "draw", value: 1}, {name: "destroyBase"}},
+			},
+		},
+	}
+}
+
+// energyShield is persistent: it stays in play and gives its bonus at the
+// start of every turn
+func energyShield() *CardEntry {
+	return &CardEntry{
+		name:     "Energy Shield",
+		qty:      1,
+		faction:  Unaligned,
+		cardType: Gambit,
+		abilities: []*Ability{
+			&Ability{
+				group:   Primary,
+				player:  Current,
+				actions: changeCounter(Increase, Authority, 1),
+				effects: []Effect{{name: "authority", value: 1}},
+			},
+		},
+	}
+}
+
+// frontierFleet is persistent: it stays in play and gives its bonus at the
+// start of every turn
+func frontierFleet() *CardEntry {
+	return &CardEntry{
+		name:     "Frontier Fleet",
+		qty:      1,
+		faction:  Unaligned,
+		cardType: Gambit,
+		abilities: []*Ability{
+			&Ability{
+				group:   Primary,
+				player:  Current,
+				actions: changeCounter(Increase, Combat, 1),
+				effects: []Effect{{name: "combat", value: 1}},
+			},
+		},
+	}
+}
+
+func politicalManeuver() *CardEntry {
+	return &CardEntry{
+		name:     "Political Maneuver",
+		qty:      1,
+		faction:  Unaligned,
+		cardType: Gambit,
+		abilities: []*Ability{
+			&Ability{
+				group:      Primary,
+				actionType: Activated,
+				id:         Utilization,
+				player:     Current,
+				actions:    changeCounter(Increase, Trade, 2),
+				effects:    []Effect{{name: "trade", value: 2}},
+			},
+		},
+	}
+}
+
+func riseToPower() *CardEntry {
+	return &CardEntry{
+		name:     "Rise to Power",
+		qty:      1,
+		faction:  Unaligned,
+		cardType: Gambit,
+		abilities: []*Ability{
+			&Ability{
+				group:      Primary,
+				actionType: Activated,
+				id:         Utilization,
+				player:     Current,
+				actions: func(player PlayerId, cardId string, state *State, logger *slog.Logger) []StateAction {
+					actions := changeCounter(Increase, Authority, 8)(player, cardId, state, logger)
+					return append(actions, drawCard(player, cardId, state, logger)...)
+				},
+				effects: []Effect{{name: "authority", value: 8}, {name: "draw", value: 1}},
+			},
+		},
+	}
+}
+
+func salvageOperation() *CardEntry {
+	return &CardEntry{
+		name:     "Salvage Operation",
+		qty:      1,
+		faction:  Unaligned,
+		cardType: Gambit,
+		abilities: []*Ability{
+			&Ability{
+				group:      Primary,
+				actionType: Activated,
+				id:         Utilization,
+				player:     Current,
+				actions:    actionRequest(ScrapCard),
+				effects:    []Effect{{name: "scrapHandOrDiscard"}},
+			},
+		},
+	}
+}
+
+func smugglingRun() *CardEntry {
+	return &CardEntry{
+		name:     "Smuggling Run",
+		qty:      1,
+		faction:  Unaligned,
+		cardType: Gambit,
+		abilities: []*Ability{
+			&Ability{
+				group:      Primary,
+				actionType: Activated,
+				id:         Utilization,
+				player:     Current,
+				actions:    actionRequest(AcquireShipForFree),
+				effects:    []Effect{{name: "acquireShip"}},
+			},
+		},
+	}
+}
+
+func surpriseAssault() *CardEntry {
+	return &CardEntry{
+		name:     "Surprise Assault",
+		qty:      1,
+		faction:  Unaligned,
+		cardType: Gambit,
+		abilities: []*Ability{
+			&Ability{
+				group:      Primary,
+				actionType: Activated,
+				id:         Utilization,
+				player:     Current,
+				actions:    changeCounter(Increase, Combat, 8),
+				effects:    []Effect{{name: "combat", value: 8}},
+			},
+		},
+	}
+}
+
+func unlikelyAlliance() *CardEntry {
+	return &CardEntry{
+		name:     "Unlikely Alliance",
+		qty:      1,
+		faction:  Unaligned,
+		cardType: Gambit,
+		abilities: []*Ability{
+			&Ability{
+				group:      Primary,
+				actionType: Activated,
+				id:         Utilization,
+				player:     Current,
+				actions: func(player PlayerId, cardId string, state *State, logger *slog.Logger) []StateAction {
+					actions := drawCard(player, cardId, state, logger)
+					return append(actions, drawCard(player, cardId, state, logger)...)
+				},
+				effects: []Effect{{name: "draw", value: 2}},
+			},
+		},
+	}
+}
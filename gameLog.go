@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LogEntry is a narrated step of the game, the structured fields allow
+// clients to render it their own way
+type LogEntry struct {
+	Player   PlayerId    `json:"player"`
+	Action   UserAction  `json:"action"`
+	CardIds  []string    `json:"cardIds,omitempty"`
+	SourceId string      `json:"sourceId,omitempty"`
+	Target   PlayerId    `json:"target,omitempty"`
+	Value    int         `json:"value,omitempty"`
+	Effects  []LogEffect `json:"effects,omitempty"`
+	Text     string      `json:"text"`
+}
+
+type LogEffect struct {
+	Player PlayerId `json:"player"`
+	Effect string   `json:"effect"`
+	Value  int      `json:"value"`
+}
+
+var counterNames = map[Counter]string{
+	Trade:      "trade",
+	Combat:     "combat",
+	Authority:  "authority",
+	Discard:    "discard",
+	ShipsOnTop: "ships on top",
+}
+
+// The draws are reported along with the counters
+const cardsEffect = "cards"
+
+func (m *Middleware) cardName(id string) string {
+	card, ok := (*m.deck)[strings.Split(id, "_")[0]]
+	if !ok {
+		return id
+	}
+	return card.name
+}
+
+func (m *Middleware) cardNames(ids []string) string {
+	names := make([]string, 0, len(ids))
+	for _, id := range ids {
+		names = append(names, m.cardName(id))
+	}
+	return strings.Join(names, ", ")
+}
+
+func (m *Middleware) playerName(player PlayerId) string {
+	if m.challenge != nil && player == ChallengeSeat {
+		return m.challenge.Name
+	}
+	return fmt.Sprintf("Player %d", player)
+}
+
+func locationName(location CardLocation) string {
+	switch {
+	case location == TradeRow:
+		return "the trade row"
+	case isHand(location):
+		return "the hand"
+	}
+	player, ok := ownerOf(location)
+	if ok && seatLocations[player].Discard == location {
+		return "the discard pile"
+	}
+	return "play"
+}
+
+// effects sums up the counter changes and draws made by the actions
+func effects(actions []StateAction) []LogEffect {
+	result := []LogEffect{}
+	add := func(player PlayerId, effect string, value int) {
+		for i := range result {
+			if result[i].Player == player && result[i].Effect == effect {
+				result[i].Value += value
+				return
+			}
+		}
+		result = append(result, LogEffect{Player: player, Effect: effect, Value: value})
+	}
+	for _, action := range actions {
+		switch a := action.(type) {
+		case *StateActionChangeCounterValue:
+			name, ok := counterNames[a.counter]
+			if !ok {
+				continue
+			}
+			switch a.operation {
+			case Increase:
+				add(a.player, name, a.value)
+			case Decrease:
+				add(a.player, name, -a.value)
+			}
+		case *StateActionTopCard:
+			player, ok := ownerOf(a.from)
+			if ok && seatLocations[player].Deck == a.from && seatLocations[player].Hand == a.to {
+				add(player, cardsEffect, 1)
+			}
+		}
+	}
+	return result
+}
+
+func (m *Middleware) effectsText(player PlayerId, effects []LogEffect) string {
+	parts := []string{}
+	for _, effect := range effects {
+		if effect.Value == 0 {
+			continue
+		}
+		text := fmt.Sprintf("%+d %s", effect.Value, effect.Effect)
+		if effect.Player != player {
+			text = fmt.Sprintf("%s %s", m.playerName(effect.Player), text)
+		}
+		parts = append(parts, text)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", strings.Join(parts, ", "))
+}
+
+// narrate describes the accepted user action, the actions are the ones made
+// by the middleware for it
+func (m *Middleware) narrate(userAction UserAction, player PlayerId, parsed []string, request ActionRequest, state *State, actions []StateAction) *LogEntry {
+	entry := LogEntry{
+		Player:   player,
+		Action:   userAction,
+		CardIds:  parsed[1:],
+		SourceId: request.CardId,
+	}
+	name := m.playerName(player)
+	source := m.cardName(request.CardId)
+	cards := m.cardNames(entry.CardIds)
+	location := ""
+	if len(entry.CardIds) > 0 {
+		if card, ok := state.Cards[entry.CardIds[0]]; ok {
+			location = locationName(card.Location)
+		}
+	}
+	owner := ""
+	if len(entry.CardIds) > 0 {
+		if card, ok := state.Cards[entry.CardIds[0]]; ok {
+			if target, ok := ownerOf(card.Location); ok {
+				entry.Target = target
+				owner = m.playerName(target)
+			}
+		}
+	}
+
+	switch userAction {
+	case Play:
+		entry.Effects = effects(actions)
+		entry.Text = fmt.Sprintf("%s played %s", name, cards)
+	case ActivateAbility:
+		entry.CardIds = parsed[1:2]
+		cards = m.cardNames(entry.CardIds)
+		entry.Effects = effects(actions)
+		if len(parsed) > 2 && parsed[2] == fmt.Sprint(int(Utilization)) {
+			entry.Text = fmt.Sprintf("%s scrapped %s", name, cards)
+		} else {
+			entry.Text = fmt.Sprintf("%s activated %s", name, cards)
+		}
+	case Start:
+		entry.Effects = effects(actions)
+		entry.Text = fmt.Sprintf("%s started the turn", name)
+	case End:
+		entry.Text = fmt.Sprintf("%s ended the turn", name)
+	case Damage:
+		entry.CardIds = nil
+		for _, action := range actions {
+			if a, ok := action.(*StateActionChangeCounterValue); ok && a.counter == Authority && a.player != player {
+				entry.Target = a.player
+				entry.Value = a.value
+			}
+		}
+		entry.Text = fmt.Sprintf("%s dealt %d damage to %s", name, entry.Value, m.playerName(entry.Target))
+	case Buy:
+		if card, ok := (*m.deck)[strings.Split(parsed[1], "_")[0]]; ok {
+			entry.Value = card.cost
+		}
+		entry.Text = fmt.Sprintf("%s bought %s for %d", name, cards, entry.Value)
+	case DestroyBase:
+		entry.Text = fmt.Sprintf("%s destroyed %s of %s", name, cards, owner)
+	case DiscardCard:
+		entry.Text = fmt.Sprintf("%s discarded %s", name, cards)
+	case ScrapCard, ScrapCardTradeRow, ScrapCardInHand:
+		if len(entry.CardIds) == 0 {
+			entry.Text = fmt.Sprintf("%s scrapped nothing", source)
+		} else {
+			entry.Effects = effects(actions)
+			entry.Text = fmt.Sprintf("%s scrapped %s from %s", source, cards, location)
+		}
+	case DestroyBaseForFree, DestroyBaseBlobDestroyer:
+		if len(entry.CardIds) == 0 {
+			entry.Text = fmt.Sprintf("%s destroyed nothing", source)
+		} else {
+			entry.Text = fmt.Sprintf("%s destroyed %s of %s", source, cards, owner)
+		}
+	case AcquireShipForFree:
+		entry.Text = fmt.Sprintf("%s acquired %s for free", source, cards)
+	case ActivateBrainWorld:
+		entry.Effects = effects(actions)
+		entry.Text = fmt.Sprintf("%s scrapped %s", source, cards)
+	case ActivateRecyclingStation:
+		entry.Effects = effects(actions)
+		entry.Text = fmt.Sprintf("%s discarded %s", source, cards)
+	case ActivateMechWorld:
+		entry.Effects = effects(actions)
+		entry.Text = fmt.Sprintf("%s counted as an ally for all factions", source)
+	case ActivateNeedle:
+		entry.Effects = effects(actions)
+		entry.Text = fmt.Sprintf("%s copied %s", m.cardName(NEEDLE_ID), cards)
+	default:
+		return nil
+	}
+	entry.Text += m.effectsText(player, entry.Effects)
+	return &entry
+}
+
+func (m *Middleware) log(entry LogEntry, actions *[]StateAction) {
+	*actions = append(*actions, &StateActionAddLogEntry{
+		entry: entry,
+	})
+}
@@ -1,14 +1,45 @@
 package main
 
 import (
-	"log"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
+// Sent by the clients which have missed a state version to get the snapshot
+const resyncMessage = "sync"
+
+// Actions are prefixed with the version of the state they are based on, e.g.
+// "@12:1,scout_3". Actions without it or based on a stale version are
+// rejected, a repeated message can't be applied twice.
+const versionPrefix = "@"
+
+type Notification struct {
+	client  *Client
+	message []byte
+}
+
+type RejectionMessage struct {
+	Error   string `json:"error"`
+	Version int    `json:"version"`
+}
+
 // Hub maintains the set of active clients and process actions
 type Hub struct {
+	name string
+
 	// Registered clients.
 	clients map[*Client]bool
 
+	// Guards the clients, they are registered by the hub goroutine and sent
+	// to by the broadcast one, see deliver
+	clientsMutex sync.Mutex
+
 	// Inbound action from the clients.
 	action chan Action
 
@@ -17,61 +48,466 @@ type Hub struct {
 
 	// Unregister requests from clients.
 	unregister chan *Client
+
+	// Snapshot requests from clients.
+	resync chan *Client
+
+	// Messages for a single client.
+	notify chan Notification
+
+	options GameOptions
+
+	store *Store
+
+	// Guards the history and the finished flag read by the http handlers
+	mutex sync.Mutex
+
+	// Set when the game is over, further actions are ignored
+	finished bool
+
+	// Set by the operators, the actions are rejected meanwhile
+	paused bool
+
+	// Set when the state manager stops applying the actions, for good
+	halted bool
+
+	// Version of the last state sent to the clients, logged with the clients
+	version int
+
+	// Operator commands, see command
+	admin chan func(stateManager *StateManager)
+
+	// Every handled user action, exported along with the options
+	history []RecordedAction
+
+	// Replayed before the clients are served when the game is imported
+	replay []RecordedAction
+
+	// Loaded instead of the deal, the game can't be exported then
+	scenario *Scenario
+
+	// Accounts of the seats, a claimed seat is served only to its account
+	accounts map[PlayerId]string
+
+	started time.Time
+
+	// In the order of elimination, used to place the players
+	eliminated []PlayerId
+
+	// Told the winner when the game is over
+	tournament *Tournament
+
+	access HubAccess
+
+	// Carries the hub name, the level can be changed while the game runs
+	logger   *slog.Logger
+	logLevel *slog.LevelVar
 }
 
-func newHub() *Hub {
+func newHub(name string, options GameOptions, store *Store) *Hub {
+	logLevel := new(slog.LevelVar)
+	logLevel.Set(defaultLogLevel.Level())
 	return &Hub{
+		name:       name,
+		options:    options,
+		store:      store,
 		action:     make(chan Action),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
+		resync:     make(chan *Client),
+		notify:     make(chan Notification),
+		admin:      make(chan func(stateManager *StateManager)),
 		clients:    make(map[*Client]bool),
+		accounts:   make(map[PlayerId]string),
+		access: HubAccess{
+			invites:  make(map[string]PlayerId),
+			seatKeys: make(map[PlayerId]string),
+		},
+		logger:   newLogger(logLevel).With("hub", name),
+		logLevel: logLevel,
 	}
 }
 
+func (h *Hub) seatAccounts() map[PlayerId]string {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	accounts := make(map[PlayerId]string)
+	for player, account := range h.accounts {
+		accounts[player] = account
+	}
+	return accounts
+}
+
 func (h *Hub) run() {
-	log.Println("run state manager")
+	h.logger.Info("run state manager", "players", h.options.Players, "format", h.options.Format)
+	h.started = time.Now()
+
+	deck := gameDeck(h.options)
+	stateManager := newStateManager(deck, h.options)
+	stateManager.logger = h.logger
+	stateManager.onHalt = h.halt
+	middleware := newMiddleware(deck, h.options)
 
-	deck := getDeck()
-	stateManager := newStateManager(deck)
-	middleware := newMiddleware(deck)
+	if h.scenario != nil {
+		// Loaded before the state manager runs, it was validated already
+		err := h.scenario.load(stateManager.state, middleware)
+		if err != nil {
+			h.logger.Error("scenario not loaded", "err", err)
+		}
+	}
 
 	go stateManager.run()
-	go h.broadcast(&stateManager.json)
-	pActions := middleware.prepareState()
-	for _, a := range pActions {
-		stateManager.action <- a
+	go h.broadcast(stateManager.updates)
+	if h.scenario == nil {
+		pActions := middleware.prepareState()
+		for _, a := range pActions {
+			stateManager.action <- a
+		}
+	}
+	for _, recorded := range h.replay {
+		h.handle(recorded.player, recorded.message, h.stateVersion(stateManager), middleware, stateManager)
 	}
 	for {
 		select {
 		case client := <-h.register:
-			log.Println("register")
-			h.clients[client] = true
+			h.clientLogger(client).Info("register")
+			h.addClient(client)
 			stateManager.action <- &StateActionGetState{}
 		case client := <-h.unregister:
-			log.Println("unregister")
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.send)
-			}
+			h.clientLogger(client).Info("unregister")
+			h.removeClient(client)
+		case command := <-h.admin:
+			command(stateManager)
 		case action := <-h.action:
-			actions := middleware.handle(string(action.message), action.client.playerId, stateManager.state)
-			for _, a := range actions {
-				stateManager.action <- a
+			if string(action.message) == resyncMessage {
+				h.resync <- action.client
+				continue
+			}
+			if h.isFinished() {
+				rejectedActions.WithLabelValues(rejectedFinished).Inc()
+				continue
+			}
+			if status := h.status(); status == Paused || status == Halted {
+				h.reject(action.client, fmt.Sprintf("the game is %s", status), h.stateVersion(stateManager))
+				reason := rejectedPaused
+				if status == Halted {
+					reason = rejectedHalted
+				}
+				rejectedActions.WithLabelValues(reason).Inc()
+				continue
+			}
+			current := h.stateVersion(stateManager)
+			basedOn, message, err := parseVersioned(string(action.message))
+			if err != nil {
+				h.clientLogger(action.client).Warn("message not parsed", "err", err)
+				h.reject(action.client, "the action should carry the state version", current)
+				rejectedActions.WithLabelValues(rejectedMalformed).Inc()
+				continue
+			}
+			if basedOn != current {
+				h.clientLogger(action.client).Info("stale action", "action", actionLabel(message), "basedOn", basedOn, "current", current)
+				h.reject(action.client, "stale action", current)
+				rejectedActions.WithLabelValues(rejectedStale).Inc()
+				continue
+			}
+			err = h.handle(action.client.playerId, message, current, middleware, stateManager)
+			if err != nil {
+				h.reject(action.client, err.Error(), current)
+			}
+		}
+	}
+}
+
+// handle passes on the state actions of the message based on the version,
+// the refused move is returned and changes nothing
+func (h *Hub) handle(player PlayerId, message string, version int, middleware *Middleware, stateManager *StateManager) error {
+	started := time.Now()
+	logger := h.logger.With("seat", player, "account", h.seatAccounts()[player], "action", actionLabel(message), "version", version)
+	middleware.logger = logger
+	actions, err := middleware.safeHandle(message, player, stateManager.state)
+	var wrongMove *WrongMoveError
+	if errors.As(err, &wrongMove) {
+		logger.Info("move refused", "message", message, "err", err)
+		rejectedActions.WithLabelValues(rejectedIllegal).Inc()
+		return err
+	}
+	if err != nil {
+		logger.Error("message not handled", "err", err)
+		rejectedActions.WithLabelValues(rejectedPanic).Inc()
+		return nil
+	}
+	logger.Debug("handled", "message", message, "stateActions", len(actions))
+	defer observeAction(message, started)
+	h.mutex.Lock()
+	h.history = append(h.history, RecordedAction{
+		player:  player,
+		message: message,
+	})
+	h.mutex.Unlock()
+	for _, a := range actions {
+		stateManager.action <- a
+		switch a := a.(type) {
+		case *StateActionEliminatePlayer:
+			h.eliminated = append(h.eliminated, a.player)
+		case *StateActionRestoreState:
+			h.eliminated = stillEliminated(h.eliminated, a.state)
+		case *StateActionGameOver:
+			h.gameOver(a.winner, a.team, stateManager.state)
+		}
+	}
+	return nil
+}
+
+// stateVersion asks the state manager for the version once the actions sent
+// before are applied
+func (h *Hub) stateVersion(stateManager *StateManager) int {
+	version := make(chan int)
+	stateManager.action <- &StateActionGetVersion{version: version}
+	return <-version
+}
+
+// stillEliminated keeps the order of the players eliminated in the restored
+// state, undoing an attack brings the player back
+func stillEliminated(eliminated []PlayerId, state *State) []PlayerId {
+	kept := []PlayerId{}
+	for _, player := range eliminated {
+		if state.counters(player).Eliminated {
+			kept = append(kept, player)
+		}
+	}
+	return kept
+}
+
+// halt is called by the state manager which stopped applying the actions
+func (h *Hub) halt() {
+	h.versionLogger().Error("the game is halted")
+	h.mutex.Lock()
+	h.halted = true
+	h.mutex.Unlock()
+}
+
+func (h *Hub) isFinished() bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.finished
+}
+
+// export writes the game in the notation, only finished games are exported
+// since the seed reveals the order of every deck
+func (h *Hub) export() (string, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if !h.finished || h.scenario != nil {
+		return "", false
+	}
+	return exportNotation(h.options, h.history), true
+}
+
+// gameOver records the result once, the game may have been ended by an
+// operator already
+func (h *Hub) gameOver(winner PlayerId, team Team, state *State) {
+	logger := h.versionLogger()
+	h.mutex.Lock()
+	if h.finished {
+		h.mutex.Unlock()
+		logger.Info("game over after the end", "winner", winner, "team", team)
+		return
+	}
+	h.finished = true
+	h.mutex.Unlock()
+	logger.Info("game over", "winner", winner, "team", team)
+	// The imported games were already recorded where they were played
+	if h.replay != nil {
+		return
+	}
+	finishedGames.WithLabelValues(gameResult(h.options, winner, team)).Inc()
+	if h.options.Challenge != "" {
+		err := h.store.recordChallenge(h.seatAccounts()[FirstPlayer], h.options.Challenge, winner != ChallengeSeat)
+		if err != nil {
+			logger.Error("challenge not recorded", "err", err)
+		}
+		return
+	}
+	seats, ok := h.matchSeats(winner, team, state)
+	if ok && !h.options.Unrated {
+		err := h.store.recordMatch(h.name, seats, time.Since(h.started))
+		if err != nil {
+			logger.Error("match not recorded", "err", err)
+		}
+	}
+	if h.tournament != nil {
+		h.tournament.recordResult(h.name, h.seatAccounts()[winner])
+	}
+}
+
+// matchSeats places the players, a game is rated only when every seat was
+// taken by a different account
+func (h *Hub) matchSeats(winner PlayerId, team Team, state *State) ([]MatchSeat, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	deck := gameDeck(h.options)
+	seats := []MatchSeat{}
+	taken := make(map[string]bool)
+	for i := 1; i <= h.options.Players; i++ {
+		player := PlayerId(i)
+		account, ok := h.accounts[player]
+		if !ok || taken[account] {
+			return nil, false
+		}
+		taken[account] = true
+		seat := MatchSeat{
+			account:  account,
+			team:     teamOf(state, player),
+			place:    2,
+			factions: factionMix(deck, state, player),
+		}
+		for k, eliminated := range h.eliminated {
+			if eliminated == player {
+				seat.place = h.options.Players - k
 			}
 		}
+		if player == winner || (team != NoTeam && seat.team == team) {
+			seat.place = 1
+		}
+		seats = append(seats, seat)
+	}
+	return seats, true
+}
+
+// versionLogger carries the version of the last state sent to the clients
+func (h *Hub) versionLogger() *slog.Logger {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.logger.With("version", h.version)
+}
+
+// clientLogger carries the seat and the account of the client
+func (h *Hub) clientLogger(client *Client) *slog.Logger {
+	return h.versionLogger().With("seat", client.playerId, "account", h.seatAccounts()[client.playerId], "role", client.role)
+}
+
+func (h *Hub) reject(client *Client, reason string, version int) {
+	message, err := json.Marshal(RejectionMessage{
+		Error:   reason,
+		Version: version,
+	})
+	if err != nil {
+		h.clientLogger(client).Error("rejection not encoded", "err", err)
+		return
 	}
+	h.notify <- Notification{client: client, message: message}
 }
 
-func (h *Hub) broadcast(channel *chan []byte) {
+// parseVersioned splits the "@<version>:<action>" message
+func parseVersioned(message string) (int, string, error) {
+	if !strings.HasPrefix(message, versionPrefix) {
+		return 0, "", &WrongMessageError{message}
+	}
+	parsed := strings.SplitN(strings.TrimPrefix(message, versionPrefix), ":", 2)
+	if len(parsed) < 2 {
+		return 0, "", &WrongMessageError{message}
+	}
+	version, err := strconv.Atoi(parsed[0])
+	if err != nil {
+		return 0, "", &WrongMessageError{message}
+	}
+	return version, parsed[1], nil
+}
+
+type WrongMessageError struct {
+	message string
+}
+
+func (e *WrongMessageError) Error() string {
+	return fmt.Sprintf("wrong message %q", e.message)
+}
+
+func (h *Hub) broadcast(updates chan StateUpdate) {
+	var latest StateUpdate
 	for {
-		state := <-*channel
-		for client := range h.clients {
-			select {
-			case client.send <- state:
-			default:
-				close(client.send)
-				delete(h.clients, client)
+		select {
+		case latest = <-updates:
+			h.mutex.Lock()
+			h.version = latest.Version
+			h.mutex.Unlock()
+			for _, client := range h.clientList() {
+				h.sendUpdate(client, latest)
 			}
+		case client := <-h.resync:
+			client.version = 0
+			h.sendUpdate(client, latest)
+		case notification := <-h.notify:
+			h.deliver(notification.client, notification.message)
 		}
 	}
 }
+
+func (h *Hub) sendUpdate(client *Client, update StateUpdate) {
+	var message []byte
+	switch {
+	case update.Snapshot == nil || client.version == update.Version:
+		return
+	case client.version == update.Version-1 && update.Patch != nil:
+		message = update.Patch
+	default:
+		message = update.Snapshot
+	}
+	if h.deliver(client, message) {
+		client.version = update.Version
+	}
+}
+
+func (h *Hub) addClient(client *Client) {
+	h.clientsMutex.Lock()
+	defer h.clientsMutex.Unlock()
+	h.clients[client] = true
+	connectedClients.WithLabelValues(client.role).Inc()
+}
+
+// removeClient closes the send queue of the client unless it was removed
+// already
+func (h *Hub) removeClient(client *Client) bool {
+	h.clientsMutex.Lock()
+	defer h.clientsMutex.Unlock()
+	return h.dropClient(client)
+}
+
+// dropClient is removeClient for the callers holding the clients mutex
+func (h *Hub) dropClient(client *Client) bool {
+	if _, ok := h.clients[client]; !ok {
+		return false
+	}
+	delete(h.clients, client)
+	close(client.send)
+	connectedClients.WithLabelValues(client.role).Dec()
+	return true
+}
+
+func (h *Hub) clientList() []*Client {
+	h.clientsMutex.Lock()
+	defer h.clientsMutex.Unlock()
+	list := make([]*Client, 0, len(h.clients))
+	for client := range h.clients {
+		list = append(list, client)
+	}
+	return list
+}
+
+// deliver queues the message for the registered client, the client whose
+// queue is full is dropped. The send queue is closed only under the mutex so
+// nothing is sent to a closed queue.
+func (h *Hub) deliver(client *Client, message []byte) bool {
+	h.clientsMutex.Lock()
+	defer h.clientsMutex.Unlock()
+	if _, ok := h.clients[client]; !ok {
+		return false
+	}
+	select {
+	case client.send <- message:
+		return true
+	default:
+		h.dropClient(client)
+		broadcastDrops.Inc()
+		return false
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Carries the password of the hub on the HTTP requests, the websockets send
+// it as their first message. It's kept out of the URLs which are logged.
+const PasswordHeader = "X-Hub-Password"
+
+type Visibility int
+
+const (
+	// Listed and joinable by anyone
+	Public Visibility = iota
+	// Not listed, joinable by anyone who knows the name
+	Unlisted
+	// Not listed, joinable with the password or an invite
+	Private
+)
+
+var visibilityNames = map[Visibility]string{
+	Public:   "public",
+	Unlisted: "unlisted",
+	Private:  "private",
+}
+
+// HubAccess decides who may take the seats of the hub
+type HubAccess struct {
+	visibility   Visibility
+	passwordHash []byte
+	// Creates the invites, given to the creator of the hub
+	ownerToken string
+	// Single-use invites for the seats
+	invites map[string]PlayerId
+	// Given to the anonymous players who joined with an invite so they can
+	// reconnect to the seat
+	seatKeys map[PlayerId]string
+}
+
+type WrongAccessError struct {
+	reason string
+}
+
+func (e *WrongAccessError) Error() string {
+	return fmt.Sprintf("access denied: %s", e.reason)
+}
+
+// sameToken compares the secrets in constant time
+func sameToken(given string, token string) bool {
+	return given != "" && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+func randomToken() (string, error) {
+	token := make([]byte, 24)
+	_, err := rand.Read(token)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+func newHubAccess(visibility Visibility, password string) (HubAccess, error) {
+	if visibility < Public || visibility > Private {
+		return HubAccess{}, &WrongGameOptionsError{fmt.Sprintf("unknown visibility %d", visibility)}
+	}
+	ownerToken, err := randomToken()
+	if err != nil {
+		return HubAccess{}, err
+	}
+	access := HubAccess{
+		visibility: visibility,
+		ownerToken: ownerToken,
+		invites:    make(map[string]PlayerId),
+		seatKeys:   make(map[PlayerId]string),
+	}
+	if password != "" {
+		access.passwordHash, err = bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return HubAccess{}, err
+		}
+	}
+	return access, nil
+}
+
+func (h *Hub) isListed() bool {
+	return h.access.visibility == Public
+}
+
+func (h *Hub) invite(ownerToken string, player PlayerId) (string, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if !sameToken(ownerToken, h.access.ownerToken) {
+		return "", &WrongAccessError{"only the owner may invite"}
+	}
+	if player < FirstPlayer || int(player) > h.options.Players {
+		return "", &WrongAccessError{fmt.Sprintf("no seat %d", player)}
+	}
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	h.access.invites[token] = player
+	return token, nil
+}
+
+func seatCookie(hub string, player PlayerId) string {
+	return fmt.Sprintf("seat_%s_%d", hub, player)
+}
+
+// Admission lets the request join the seat, nothing is claimed until the
+// connection is upgraded
+type Admission struct {
+	player PlayerId
+	// Sent with the websocket upgrade
+	header http.Header
+	// The seat was free, it may have been taken since
+	free bool
+	// The password is read from the first message of the connection
+	password bool
+	// Used up by the claim
+	invite  string
+	seatKey string
+	account *Account
+}
+
+// admit checks the request joining the seat
+func (h *Hub) admit(player PlayerId, account *Account, r *http.Request) (*Admission, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	admission := &Admission{player: player, header: http.Header{}, account: account}
+	claimed, isClaimed := h.accounts[player]
+	if isClaimed {
+		if account == nil || claimed != account.Id {
+			return nil, &WrongAccessError{"the seat belongs to another account"}
+		}
+		return admission, nil
+	}
+	if key, ok := h.access.seatKeys[player]; ok {
+		cookie, err := r.Cookie(seatCookie(h.name, player))
+		if err != nil || !sameToken(cookie.Value, key) {
+			return nil, &WrongAccessError{"the seat is taken"}
+		}
+		return admission, nil
+	}
+
+	admission.free = true
+	invite := r.URL.Query().Get("invite")
+	invited, ok := h.access.invites[invite]
+	switch {
+	case invite != "" && ok && invited == player:
+		admission.invite = invite
+	case h.access.passwordHash != nil:
+		admission.password = true
+	case h.access.visibility == Private:
+		return nil, &WrongAccessError{"an invite is required"}
+	}
+	// The anonymous player keeps the seat with the key of the cookie, like
+	// the account keeps its seat
+	if account == nil {
+		key, err := randomToken()
+		if err != nil {
+			return nil, err
+		}
+		admission.seatKey = key
+		cookie := &http.Cookie{
+			Name:     seatCookie(h.name, player),
+			Value:    key,
+			Path:     "/",
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		}
+		admission.header.Add("Set-Cookie", cookie.String())
+	}
+	return admission, nil
+}
+
+// readPassword checks the password sent as the first message of the
+// upgraded connection
+func (h *Hub) readPassword(conn *websocket.Conn) error {
+	conn.SetReadDeadline(time.Now().Add(config.PongWait))
+	_, message, err := conn.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return h.checkPassword(string(message))
+}
+
+func (h *Hub) checkPassword(password string) error {
+	h.mutex.Lock()
+	hash := h.access.passwordHash
+	h.mutex.Unlock()
+	if bcrypt.CompareHashAndPassword(hash, []byte(password)) != nil {
+		return &WrongAccessError{"wrong password"}
+	}
+	return nil
+}
+
+// mayView checks the request reading a game of the hub, the hubs which need
+// a password or an invite to join are read only by their players and owner
+func (h *Hub) mayView(account *Account, r *http.Request) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.access.visibility != Private && h.access.passwordHash == nil {
+		return nil
+	}
+	if sameToken(r.URL.Query().Get("owner"), h.access.ownerToken) {
+		return nil
+	}
+	for _, claimed := range h.accounts {
+		if account != nil && claimed == account.Id {
+			return nil
+		}
+	}
+	for player, key := range h.access.seatKeys {
+		cookie, err := r.Cookie(seatCookie(h.name, player))
+		if err == nil && sameToken(cookie.Value, key) {
+			return nil
+		}
+	}
+	if h.access.passwordHash != nil {
+		err := bcrypt.CompareHashAndPassword(h.access.passwordHash, []byte(r.Header.Get(PasswordHeader)))
+		if err == nil {
+			return nil
+		}
+	}
+	return &WrongAccessError{"only the players may read the game"}
+}
+
+// claim takes the seat for the account of the upgraded connection and uses
+// up the invite, the seat may have been taken since the admission
+func (h *Hub) claim(admission *Admission) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	player := admission.player
+	claimed, isClaimed := h.accounts[player]
+	if isClaimed && (admission.account == nil || claimed != admission.account.Id) {
+		return &WrongAccessError{"the seat belongs to another account"}
+	}
+	if _, keyed := h.access.seatKeys[player]; keyed && admission.free {
+		return &WrongAccessError{"the seat is taken"}
+	}
+	if admission.invite != "" {
+		if invited, ok := h.access.invites[admission.invite]; !ok || invited != player {
+			return &WrongAccessError{"the invite was used already"}
+		}
+		delete(h.access.invites, admission.invite)
+	}
+	if admission.seatKey != "" {
+		h.access.seatKeys[player] = admission.seatKey
+	}
+	if admission.account != nil {
+		h.accounts[player] = admission.account.Id
+	}
+	return nil
+}
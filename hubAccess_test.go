@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+// The anonymous player who joined first keeps the seat with the cookie
+func TestAnonymousSeatIsKept(t *testing.T) {
+	hub := newHub("kept", GameOptions{Players: MinPlayers}, nil)
+	request := httptest.NewRequest("GET", "/hubs/kept?player=1", nil)
+	first, err := hub.admit(FirstPlayer, nil, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := hub.admit(FirstPlayer, nil, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = hub.claim(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hub.claim(second) == nil {
+		t.Error("the seat is claimed twice")
+	}
+	if _, err := hub.admit(FirstPlayer, nil, request); err == nil {
+		t.Error("the seat is admitted without the cookie")
+	}
+	rejoin := httptest.NewRequest("GET", "/hubs/kept?player=1", nil)
+	rejoin.Header.Set("Cookie", first.header.Get("Set-Cookie"))
+	if _, err := hub.admit(FirstPlayer, nil, rejoin); err != nil {
+		t.Errorf("the seat isn't admitted with the cookie: %v", err)
+	}
+}
+
+// The password is checked once the connection is upgraded, it isn't taken
+// from the URL
+func TestPasswordIsReadAfterTheUpgrade(t *testing.T) {
+	hub := newHub("locked", GameOptions{Players: MinPlayers}, nil)
+	var err error
+	hub.access, err = newHubAccess(Unlisted, "secret password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	admission, err := hub.admit(SecondPlayer, nil, httptest.NewRequest("GET", "/hubs/locked?player=2&password=secret+password", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !admission.password {
+		t.Error("the password isn't asked")
+	}
+	if hub.checkPassword("wrong") == nil {
+		t.Error("a wrong password is accepted")
+	}
+	if err := hub.checkPassword("secret password"); err != nil {
+		t.Error(err)
+	}
+}
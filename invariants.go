@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+)
+
+type WrongStateError struct {
+	action ActionMessage
+	reason string
+}
+
+func (e *WrongStateError) Error() string {
+	return fmt.Sprintf("wrong state after action %v: %s", e.action, e.reason)
+}
+
+// checkState verifies the state after the action, the new violations are
+// logged with the action and the state manager stops applying the actions
+// if it should halt
+func (s *StateManager) checkState(action StateAction) {
+	violations := make(map[string]bool)
+	for _, reason := range s.state.violations(s.totalCards) {
+		violations[reason] = true
+		if s.violations[reason] {
+			continue
+		}
+		s.logger.Error("wrong state", "err", &WrongStateError{EncodeAction(action), reason}, "version", s.version)
+		if s.halt && !s.halted {
+			s.halted = true
+			if s.onHalt != nil {
+				s.onHalt()
+			}
+		}
+	}
+	s.violations = violations
+}
+
+// violations lists the broken invariants of the state, the game started
+// with the given number of cards
+func (s *State) violations(totalCards int) []string {
+	violations := []string{}
+	if len(s.Cards) != totalCards {
+		violations = append(violations, fmt.Sprintf("%d cards instead of %d", len(s.Cards), totalCards))
+	}
+	seen := make(map[string]CardLocation)
+	piled := 0
+	for location, pile := range s.piles {
+		piled += len(pile)
+		for i, id := range pile {
+			card, ok := s.Cards[id]
+			if !ok {
+				violations = append(violations, fmt.Sprintf("unknown card %s in location %d", id, location))
+				continue
+			}
+			if other, ok := seen[id]; ok {
+				violations = append(violations, fmt.Sprintf("card %s is in locations %d and %d", id, other, location))
+			}
+			seen[id] = location
+			if card.Location != location {
+				violations = append(violations, fmt.Sprintf("card %s is in location %d but says %d", id, location, card.Location))
+			}
+			// Contiguous and unique indexes make the top card the one with
+			// the index equal to the size of the pile, the slots only grow
+			switch {
+			case keepsSlots(location) && i > 0 && card.Index <= s.Cards[pile[i-1]].Index:
+				violations = append(violations, fmt.Sprintf("card %s has index %d below the slot of the card under it in location %d", id, card.Index, location))
+			case !keepsSlots(location) && card.Index != i+1:
+				violations = append(violations, fmt.Sprintf("card %s has index %d at position %d of location %d", id, card.Index, i+1, location))
+			}
+		}
+	}
+	if piled != len(s.Cards) {
+		for id, card := range s.Cards {
+			if _, ok := seen[id]; !ok {
+				violations = append(violations, fmt.Sprintf("card %s of location %d is in no pile", id, card.Location))
+			}
+		}
+	}
+	checked := make(map[*Counters]bool)
+	for i := 1; i <= s.Players; i++ {
+		c := s.counters(PlayerId(i))
+		// Teammates share the counters in Hydra
+		if c == nil || checked[c] {
+			continue
+		}
+		checked[c] = true
+		counters := []struct {
+			name  string
+			value int
+		}{
+			{"trade", c.Trade},
+			{"combat", c.Combat},
+			{"discard", c.Discard},
+			{"shipsOnTop", c.ShipsOnTop},
+			{"fleetFlag", c.fleetFlag},
+			{"blobs", c.blobs},
+		}
+		for _, counter := range counters {
+			if counter.value < 0 {
+				violations = append(violations, fmt.Sprintf("%s of player %d is %d", counter.name, i, counter.value))
+			}
+		}
+	}
+	return violations
+}
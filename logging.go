@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+)
+
+// logHandler writes every level, the loggers decide what goes through it
+var logHandler slog.Handler = slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug})
+
+// The level of the server and of the new hubs
+var defaultLogLevel = new(slog.LevelVar)
+
+// setupLogging makes the default logger from the validated config, the log
+// package writes through it as well
+func setupLogging() {
+	defaultLogLevel.UnmarshalText([]byte(config.LogLevel))
+	options := &slog.HandlerOptions{Level: slog.LevelDebug}
+	if config.LogFormat == "json" {
+		logHandler = slog.NewJSONHandler(os.Stderr, options)
+	} else {
+		logHandler = slog.NewTextHandler(os.Stderr, options)
+	}
+	slog.SetDefault(newLogger(defaultLogLevel))
+}
+
+// newLogger logs the records of the level and above, the level can be
+// changed while the logger is used
+func newLogger(level slog.Leveler) *slog.Logger {
+	return slog.New(&levelHandler{level: level, handler: logHandler})
+}
+
+// levelHandler filters the records of a handler by a level of its own
+type levelHandler struct {
+	level   slog.Leveler
+	handler slog.Handler
+}
+
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level() && h.handler.Enabled(ctx, level)
+}
+
+func (h *levelHandler) Handle(ctx context.Context, record slog.Record) error {
+	return h.handler.Handle(ctx, record)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{level: h.level, handler: h.handler.WithAttrs(attrs)}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{level: h.level, handler: h.handler.WithGroup(name)}
+}
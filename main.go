@@ -3,73 +3,572 @@ package main
 import (
 	"encoding/json"
 	"flag"
+	"fmt"
 	"io/ioutil"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
 )
 
-var addr = flag.String("addr", ":8080", "http service address")
 var hubs = make(map[string]*Hub)
 
-func route(hubs map[string]*Hub, w http.ResponseWriter, r *http.Request) {
-	(w).Header().Set("Access-Control-Allow-Origin", "null")
+// Guards the hubs, the tournaments add them from the hub goroutines
+var hubsMutex sync.Mutex
+var store *Store
+
+// The cards don't change while the server runs
+var cardCatalog []byte
+
+func findHub(name string) (*Hub, bool) {
+	hubsMutex.Lock()
+	defer hubsMutex.Unlock()
+	hub, ok := hubs[name]
+	return hub, ok
+}
+
+type WrongHubError struct {
+	reason string
+}
+
+func (e *WrongHubError) Error() string {
+	return fmt.Sprintf("hub not added: %s", e.reason)
+}
+
+// addHub runs the hub unless there is one with the same name or the server
+// runs as many unfinished hubs as it may
+func addHub(name string, hub *Hub) error {
+	hubsMutex.Lock()
+	defer hubsMutex.Unlock()
+	if !hasRoom(1) {
+		return &WrongHubError{fmt.Sprintf("the limit of %d hubs is reached", config.MaxHubs)}
+	}
+	return runHub(name, hub)
+}
+
+// runHub runs the hub regardless of the limit, the hubs mutex has to be
+// held by the caller
+func runHub(name string, hub *Hub) error {
+	if _, ok := hubs[name]; ok {
+		return &WrongHubError{"hub with such name already exists"}
+	}
+	hubs[name] = hub
+	go hub.run()
+	return nil
+}
+
+// runningHubs counts the unfinished hubs, the finished ones are kept for
+// the export. The hubs mutex has to be held by the caller.
+func runningHubs() int {
+	running := 0
+	for _, hub := range hubs {
+		if !hub.isFinished() {
+			running++
+		}
+	}
+	return running
+}
+
+// hasRoom tells if the games fit under the limit along with the unfinished
+// hubs, the hubs mutex has to be held by the caller
+func hasRoom(games int) bool {
+	if config.MaxHubs == 0 {
+		return true
+	}
+	return runningHubs()+games <= config.MaxHubs
+}
+
+func route(w http.ResponseWriter, r *http.Request) {
+	(w).Header().Set("Access-Control-Allow-Origin", config.CORSOrigin)
 	(w).Header().Set("Access-Control-Allow-Credentials", "true")
 	(w).Header().Set("Access-Control-Allow-Headers", "*")
 	(w).Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, DELETE")
 
-	var hubsPattern = regexp.MustCompile("^/hubs/(\\w+)\\?player=([1,2])")
+	var hubsPattern = regexp.MustCompile("^/hubs/(\\w+)\\?player=([1-4])")
+	var exportPattern = regexp.MustCompile("^/hubs/(\\w+)/export$")
+	var importPattern = regexp.MustCompile("^/hubs/(\\w+)/import$")
+	var accountPattern = regexp.MustCompile("^/accounts/(\\w+)$")
+	var historyPattern = regexp.MustCompile("^/players/(\\w+)/history$")
+	var invitesPattern = regexp.MustCompile("^/hubs/(\\w+)/invites$")
+	var tournamentPattern = regexp.MustCompile("^/tournaments/(\\w+)(/players|/start)?$")
 	type HubData struct {
-		Name string `json:"name"`
+		Name      string              `json:"name"`
+		Gambits   bool                `json:"gambits,omitempty"`
+		Players   int                 `json:"players"`
+		Format    GameFormat          `json:"format"`
+		Challenge string              `json:"challenge,omitempty"`
+		Accounts  map[PlayerId]string `json:"accounts,omitempty"`
+		Unrated   bool                `json:"unrated,omitempty"`
+		NoUndo    bool                `json:"noUndo,omitempty"`
+		// Only given on creation
+		Visibility Visibility `json:"visibility,omitempty"`
+		Password   string     `json:"password,omitempty"`
+		Setup      *Setup     `json:"setup,omitempty"`
+	}
+	type InviteData struct {
+		OwnerToken string   `json:"ownerToken"`
+		Seat       PlayerId `json:"seat"`
+		Token      string   `json:"token,omitempty"`
+	}
+	type Credentials struct {
+		Name     string `json:"name"`
+		Password string `json:"password"`
+	}
+	type SessionData struct {
+		Token   string  `json:"token"`
+		Profile Profile `json:"profile"`
+	}
+	type TournamentOptions struct {
+		Name    string           `json:"name"`
+		Format  TournamentFormat `json:"format"`
+		Rounds  int              `json:"rounds"`
+		Gambits bool             `json:"gambits"`
+	}
+	type ChallengeData struct {
+		*Challenge
+		ChallengeRecord
 	}
 	if r.URL.Path == "/" {
 		http.ServeFile(w, r, "home.html")
 		return
 	}
+	if strings.HasPrefix(r.URL.Path, "/admin/") {
+		routeAdmin(w, r)
+		return
+	}
+	if r.URL.Path == "/metrics" && r.Method == "GET" {
+		metricsHandler.ServeHTTP(w, r)
+		return
+	}
 	if r.URL.Path == "/hubs" && r.Method == "GET" {
+		hubsMutex.Lock()
 		hubsList := make([]HubData, 0, len(hubs))
-		for name := range hubs {
-			hubsList = append(hubsList, HubData{Name: name})
+		for name, hub := range hubs {
+			if !hub.isListed() {
+				continue
+			}
+			hubsList = append(hubsList, HubData{
+				Name:      name,
+				Gambits:   hub.options.Gambits,
+				Players:   hub.options.Players,
+				Format:    hub.options.Format,
+				Challenge: hub.options.Challenge,
+				Accounts:  hub.seatAccounts(),
+				Unrated:   hub.options.Unrated,
+				NoUndo:    hub.options.NoUndo,
+			})
 		}
+		hubsMutex.Unlock()
 		var result []byte
 		result, err := json.Marshal(hubsList)
 		if err != nil {
-			log.Println(err)
+			slog.Error("request failed", "path", r.URL.Path, "err", err)
+		}
+		w.Write(result)
+		return
+	}
+	if r.URL.Path == "/accounts" && r.Method == "POST" {
+		var credentials Credentials
+		body, _ := ioutil.ReadAll(r.Body)
+		err := json.Unmarshal(body, &credentials)
+		if err != nil {
+			http.Error(w, "Wrong request", http.StatusBadRequest)
+			return
+		}
+		account, err := store.createAccount(credentials.Name, credentials.Password)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err := json.Marshal(account.profile())
+		if err != nil {
+			slog.Error("request failed", "path", r.URL.Path, "err", err)
+		}
+		w.Write(result)
+		return
+	}
+	if r.URL.Path == "/login" && r.Method == "POST" {
+		var credentials Credentials
+		body, _ := ioutil.ReadAll(r.Body)
+		err := json.Unmarshal(body, &credentials)
+		if err != nil {
+			http.Error(w, "Wrong request", http.StatusBadRequest)
+			return
+		}
+		account, err := store.authenticate(credentials.Name, credentials.Password)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		token := store.newSession(account)
+		http.SetCookie(w, &http.Cookie{
+			Name:     SessionCookie,
+			Value:    token,
+			Path:     "/",
+			MaxAge:   int(SessionDuration.Seconds()),
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+		result, err := json.Marshal(SessionData{
+			Token:   token,
+			Profile: account.profile(),
+		})
+		if err != nil {
+			slog.Error("request failed", "path", r.URL.Path, "err", err)
+		}
+		w.Write(result)
+		return
+	}
+	if r.URL.Path == "/logout" && r.Method == "POST" {
+		http.SetCookie(w, &http.Cookie{
+			Name:   SessionCookie,
+			Path:   "/",
+			MaxAge: -1,
+		})
+		return
+	}
+	if accountPattern.MatchString(r.URL.Path) && r.Method == "GET" {
+		account, ok := store.account(accountPattern.FindStringSubmatch(r.URL.Path)[1])
+		if !ok {
+			http.Error(w, "Not found", http.StatusNotFound)
+			return
+		}
+		result, err := json.Marshal(account.profile())
+		if err != nil {
+			slog.Error("request failed", "path", r.URL.Path, "err", err)
+		}
+		w.Write(result)
+		return
+	}
+	if historyPattern.MatchString(r.URL.Path) && r.Method == "GET" {
+		history, ok := store.matchHistory(historyPattern.FindStringSubmatch(r.URL.Path)[1])
+		if !ok {
+			http.Error(w, "Not found", http.StatusNotFound)
+			return
+		}
+		result, err := json.Marshal(history)
+		if err != nil {
+			slog.Error("request failed", "path", r.URL.Path, "err", err)
+		}
+		w.Write(result)
+		return
+	}
+	if r.URL.Path == "/leaderboard" && r.Method == "GET" {
+		result, err := json.Marshal(store.leaderboard())
+		if err != nil {
+			slog.Error("request failed", "path", r.URL.Path, "err", err)
+		}
+		w.Write(result)
+		return
+	}
+	if r.URL.Path == "/tournaments" && r.Method == "GET" {
+		tournamentsList := []TournamentData{}
+		for _, tournament := range listTournaments() {
+			tournamentsList = append(tournamentsList, tournament.data())
+		}
+		result, err := json.Marshal(tournamentsList)
+		if err != nil {
+			slog.Error("request failed", "path", r.URL.Path, "err", err)
+		}
+		w.Write(result)
+		return
+	}
+	if r.URL.Path == "/tournaments" && r.Method == "POST" {
+		account, err := requestAccount(r)
+		if err != nil || account == nil {
+			http.Error(w, "Login required", http.StatusUnauthorized)
+			return
+		}
+		var options TournamentOptions
+		body, _ := ioutil.ReadAll(r.Body)
+		err = json.Unmarshal(body, &options)
+		if err != nil || !accountNamePattern.MatchString(options.Name) {
+			http.Error(w, "Wrong request", http.StatusBadRequest)
+			return
+		}
+		tournament, err := newTournament(options.Name, options.Format, options.Rounds, account.Id, options.Gambits)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !addTournament(tournament) {
+			http.Error(w, "Tournament with such name already exists", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if tournamentPattern.MatchString(r.URL.Path) {
+		matches := tournamentPattern.FindStringSubmatch(r.URL.Path)
+		tournament, ok := findTournament(matches[1])
+		if !ok {
+			http.Error(w, "Not found", http.StatusNotFound)
+			return
+		}
+		if matches[2] == "" && r.Method == "GET" {
+			result, err := json.Marshal(tournament.data())
+			if err != nil {
+				slog.Error("request failed", "path", r.URL.Path, "err", err)
+			}
+			w.Write(result)
+			return
+		}
+		if r.Method == "POST" {
+			account, err := requestAccount(r)
+			if err != nil || account == nil {
+				http.Error(w, "Login required", http.StatusUnauthorized)
+				return
+			}
+			switch matches[2] {
+			case "/players":
+				err = tournament.register(account.Id)
+			case "/start":
+				err = tournament.start(account.Id)
+			default:
+				http.Error(w, "Not found", http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+	}
+	if r.URL.Path == "/cards" && r.Method == "GET" {
+		w.Write(cardCatalog)
+		return
+	}
+	if r.URL.Path == "/challenges" && r.Method == "GET" {
+		// The records are of the signed in player, the guests get none
+		account, err := requestAccount(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		challenges := getChallenges()
+		challengesList := make([]ChallengeData, 0, len(challenges))
+		for id, challenge := range challenges {
+			challengesList = append(challengesList, ChallengeData{
+				Challenge:       challenge,
+				ChallengeRecord: store.challengeRecord(account, id),
+			})
+		}
+		sort.Slice(challengesList, func(i, j int) bool {
+			return challengesList[i].Id < challengesList[j].Id
+		})
+		result, err := json.Marshal(challengesList)
+		if err != nil {
+			slog.Error("request failed", "path", r.URL.Path, "err", err)
 		}
 		w.Write(result)
 		return
 	}
 	if r.URL.Path == "/hubs" && r.Method == "POST" {
-		var hubData HubData
+		// The options the request leaves out are the configured ones
+		format, _ := config.DefaultGame.format()
+		visibility, _ := config.DefaultGame.visibility()
+		hubData := HubData{
+			Players:    config.DefaultGame.Players,
+			Format:     format,
+			Gambits:    config.DefaultGame.Gambits,
+			Unrated:    config.DefaultGame.Unrated,
+			Visibility: visibility,
+		}
 		body, _ := ioutil.ReadAll(r.Body)
 		err := json.Unmarshal(body, &hubData)
 		if err != nil {
-			log.Println(err)
+			slog.Error("request failed", "path", r.URL.Path, "err", err)
 		}
-		_, ok := hubs[hubData.Name]
+		_, ok := findHub(hubData.Name)
 		if ok {
 			http.Error(w, "Hub with such name already exists", http.StatusBadRequest)
 			return
 		}
-		hub := newHub()
-		hubs[hubData.Name] = hub
-		w.WriteHeader(http.StatusOK)
-		go hub.run()
+		if hubData.Challenge != "" {
+			_, ok := getChallenges()[hubData.Challenge]
+			if !ok {
+				http.Error(w, "Unknown challenge", http.StatusBadRequest)
+				return
+			}
+			// The second seat is taken by the scripted opponent
+			hubData.Players = MinPlayers
+			hubData.Format = FreeForAll
+		}
+		if hubData.Players == 0 {
+			hubData.Players = MinPlayers
+		}
+		err = validateSeating(hubData.Players, hubData.Format)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		options := GameOptions{
+			Gambits:   hubData.Gambits,
+			Players:   hubData.Players,
+			Format:    hubData.Format,
+			Challenge: hubData.Challenge,
+			Seed:      time.Now().UnixNano(),
+			Unrated:   hubData.Unrated,
+			NoUndo:    hubData.NoUndo,
+		}
+		if hubData.Setup != nil {
+			options.Setup = *hubData.Setup
+		}
+		err = resolveSetup(&options)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		hub := newHub(hubData.Name, options, store)
+		hub.access, err = newHubAccess(hubData.Visibility, hubData.Password)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err = addHub(hubData.Name, hub)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err := json.Marshal(InviteData{OwnerToken: hub.access.ownerToken})
+		if err != nil {
+			slog.Error("request failed", "path", r.URL.Path, "err", err)
+		}
+		w.Write(result)
+		return
+	}
+	if invitesPattern.MatchString(r.URL.Path) && r.Method == "POST" {
+		hub, ok := findHub(invitesPattern.FindStringSubmatch(r.URL.Path)[1])
+		if !ok {
+			http.Error(w, "Not found", http.StatusNotFound)
+			return
+		}
+		var invite InviteData
+		body, _ := ioutil.ReadAll(r.Body)
+		err := json.Unmarshal(body, &invite)
+		if err != nil {
+			http.Error(w, "Wrong request", http.StatusBadRequest)
+			return
+		}
+		invite.Token, err = hub.invite(invite.OwnerToken, invite.Seat)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		invite.OwnerToken = ""
+		result, err := json.Marshal(invite)
+		if err != nil {
+			slog.Error("request failed", "path", r.URL.Path, "err", err)
+		}
+		w.Write(result)
+		return
+	}
+	if exportPattern.MatchString(r.URL.Path) && r.Method == "GET" {
+		hub, ok := findHub(exportPattern.FindStringSubmatch(r.URL.Path)[1])
+		if !ok {
+			http.Error(w, "Not found", http.StatusNotFound)
+			return
+		}
+		account, err := requestAccount(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		err = hub.mayView(account, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		notation, ok := hub.export()
+		if !ok {
+			http.Error(w, "The game is not over yet", http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(notation))
+		return
+	}
+	if importPattern.MatchString(r.URL.Path) && r.Method == "POST" {
+		name := importPattern.FindStringSubmatch(r.URL.Path)[1]
+		_, ok := findHub(name)
+		if ok {
+			http.Error(w, "Hub with such name already exists", http.StatusBadRequest)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		options, history, err := importNotation(string(body))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, ok := getChallenges()[options.Challenge]; options.Challenge != "" && !ok {
+			http.Error(w, "Unknown challenge", http.StatusBadRequest)
+			return
+		}
+		err = validateSeating(options.Players, options.Format)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err = resolveSetup(&options)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		hub := newHub(name, options, store)
+		hub.replay = history
+		// The notation leaves out the access, the configured one is used
+		visibility, _ := config.DefaultGame.visibility()
+		hub.access, err = newHubAccess(visibility, "")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err = addHub(name, hub)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err := json.Marshal(InviteData{OwnerToken: hub.access.ownerToken})
+		if err != nil {
+			slog.Error("request failed", "path", r.URL.Path, "err", err)
+		}
+		w.Write(result)
 		return
 	}
 	if hubsPattern.MatchString(r.URL.Path + "?" + r.URL.RawQuery) {
 		matches := hubsPattern.FindStringSubmatch(r.URL.Path + "?" + r.URL.RawQuery)
-		hub, ok := hubs[matches[1]]
+		hub, ok := findHub(matches[1])
 		if !ok {
 			http.Error(w, "Not found", http.StatusNotFound)
 			return
 		}
-		if matches[2] == "1" {
-			serveWs(hub, FirstPlayer, w, r)
+		seat, _ := strconv.Atoi(matches[2])
+		if seat > hub.options.Players || (hub.options.Challenge != "" && PlayerId(seat) == ChallengeSeat) {
+			http.Error(w, "Not found", http.StatusNotFound)
+			return
+		}
+		account, err := requestAccount(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
 		}
-		if matches[2] == "2" {
-			serveWs(hub, SecondPlayer, w, r)
+		admission, err := hub.admit(PlayerId(seat), account, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
 		}
+		serveWs(hub, admission, clientRole(account), w, r)
 		return
 	}
 	if r.Method == "OPTIONS" {
@@ -78,16 +577,29 @@ func route(hubs map[string]*Hub, w http.ResponseWriter, r *http.Request) {
 	http.Error(w, "Not found", http.StatusNotFound)
 }
 
-func serveWs(hub *Hub, player PlayerId, w http.ResponseWriter, r *http.Request) {
-	//TODO delete CheckOrigin reasigning
-	upgrader.CheckOrigin = func(r *http.Request) bool { return true }
-
-	conn, err := upgrader.Upgrade(w, r, nil)
+func serveWs(hub *Hub, admission *Admission, role string, w http.ResponseWriter, r *http.Request) {
+	player := admission.player
+	conn, err := upgrader.Upgrade(w, r, admission.header)
 	if err != nil {
-		log.Println(err)
+		hub.logger.Warn("connection not upgraded", "seat", player, "err", err)
 		return
 	}
-	client := &Client{hub: hub, playerId: player, conn: conn, send: make(chan []byte, 256)}
+	if admission.password {
+		err = hub.readPassword(conn)
+		if err != nil {
+			hub.logger.Warn("seat not claimed", "seat", player, "err", err)
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, err.Error()))
+			conn.Close()
+			return
+		}
+	}
+	err = hub.claim(admission)
+	if err != nil {
+		hub.logger.Warn("seat not claimed", "seat", player, "err", err)
+		conn.Close()
+		return
+	}
+	client := &Client{hub: hub, playerId: player, role: role, conn: conn, send: make(chan []byte, 256)}
 	client.hub.register <- client
 
 	// Allow collection of memory referenced by the caller by doing all work in
@@ -99,11 +611,31 @@ func serveWs(hub *Hub, player PlayerId, w http.ResponseWriter, r *http.Request)
 func main() {
 	flag.Parse()
 
+	var err error
+	config, err = loadConfig(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	setupLogging()
+	slog.Info("config", "config", config)
+	store, err = newStore(config.Store)
+	if err != nil {
+		slog.Error("store not opened", "path", config.Store, "err", err)
+		os.Exit(1)
+	}
+	cardCatalog, err = json.Marshal(getCatalog())
+	if err != nil {
+		slog.Error("catalog not built", "err", err)
+		os.Exit(1)
+	}
+
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		route(hubs, w, r)
+		route(w, r)
 	})
-	err := http.ListenAndServe(*addr, nil)
+	slog.Info("listen", "addr", config.Addr)
+	err = http.ListenAndServe(config.Addr, nil)
 	if err != nil {
-		log.Fatal("ListenAndServe: ", err)
+		slog.Error("server stopped", "err", err)
+		os.Exit(1)
 	}
 }
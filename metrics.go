@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "starrealms"
+
+// The server has a registry of its own, only these metrics are exposed
+var metricsRegistry = prometheus.NewRegistry()
+
+var activeHubs = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+	Namespace: metricsNamespace,
+	Name:      "active_hubs",
+	Help:      "Number of the unfinished hubs the server runs.",
+}, func() float64 {
+	hubsMutex.Lock()
+	defer hubsMutex.Unlock()
+	return float64(runningHubs())
+})
+
+var connectedClients = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: metricsNamespace,
+	Name:      "connected_clients",
+	Help:      "Number of the clients connected to the hubs, by whether they are signed in.",
+}, []string{"role"})
+
+var handledActions = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: metricsNamespace,
+	Name:      "handled_actions_total",
+	Help:      "Number of the user actions handled by the hubs.",
+}, []string{"action"})
+
+var rejectedActions = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: metricsNamespace,
+	Name:      "rejected_actions_total",
+	Help:      "Number of the messages the hubs didn't handle, by the reason.",
+}, []string{"reason"})
+
+var broadcastDrops = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: metricsNamespace,
+	Name:      "broadcast_drops_total",
+	Help:      "Number of the clients dropped because their send queue was full.",
+})
+
+var actionLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: metricsNamespace,
+	Name:      "action_duration_seconds",
+	Help:      "Time to handle a user action and pass its state actions on.",
+	Buckets:   prometheus.DefBuckets,
+}, []string{"action"})
+
+var finishedGames = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: metricsNamespace,
+	Name:      "finished_games_total",
+	Help:      "Number of the games played to the end, by the result.",
+}, []string{"result"})
+
+var metricsHandler = promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})
+
+func init() {
+	metricsRegistry.MustRegister(
+		activeHubs,
+		connectedClients,
+		handledActions,
+		rejectedActions,
+		broadcastDrops,
+		actionLatency,
+		finishedGames,
+	)
+}
+
+// Rejection reasons
+const (
+	rejectedMalformed = "malformed"
+	rejectedStale     = "stale"
+	rejectedFinished  = "finished"
+	rejectedPaused    = "paused"
+	rejectedHalted    = "halted"
+	rejectedPanic     = "panic"
+	rejectedIllegal   = "illegal"
+)
+
+// clientRole labels the client by the account it connected with
+func clientRole(account *Account) string {
+	if account == nil {
+		return "guest"
+	}
+	return "account"
+}
+
+// actionLabel is the name of the user action of the message, the unknown
+// actions share one label so that the messages can't add labels
+func actionLabel(message string) string {
+	action, err := strconv.Atoi(strings.Split(message, ",")[0])
+	if err != nil {
+		return "unknown"
+	}
+	name, ok := userActionNames[UserAction(action)]
+	if !ok {
+		return "unknown"
+	}
+	return name
+}
+
+func observeAction(message string, started time.Time) {
+	label := actionLabel(message)
+	handledActions.WithLabelValues(label).Inc()
+	actionLatency.WithLabelValues(label).Observe(time.Since(started).Seconds())
+}
+
+// gameResult labels the finished game by who won it
+func gameResult(options GameOptions, winner PlayerId, team Team) string {
+	switch {
+	case options.Challenge != "" && winner == ChallengeSeat:
+		return "challengeLost"
+	case options.Challenge != "":
+		return "challengeWon"
+	case team != NoTeam:
+		return fmt.Sprintf("team%d", team)
+	default:
+		return fmt.Sprintf("seat%d", winner)
+	}
+}
@@ -2,15 +2,22 @@ package main
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
+	"runtime/debug"
 	"strconv"
 	"strings"
 )
 
 type Middleware struct {
 	deck         *map[string]*CardEntry
+	options      GameOptions
+	challenge    *Challenge
 	allyState    *AllyState
 	deferredCall func() []StateAction
+	// Restored by Undo, see Checkpoint
+	lastCheckpoint *Checkpoint
+	// Carries the context of the message being handled
+	logger *slog.Logger
 }
 
 type AllyState struct {
@@ -29,6 +36,8 @@ const (
 	TradeRowQty             int = 5
 	HandCardsQty            int = 5
 	FirstPlayerHandCardsQty int = 3
+	// Used for the second seat when there are more than two players
+	SecondPlayerHandCardsQty int = 4
 )
 
 type PlayerPointer int
@@ -46,11 +55,7 @@ const (
 	CurrentTable
 	CurrentDiscard
 	CurrentBases
-	OpponentDeck
-	OpponentHand
-	OpponentTable
-	OpponentDiscard
-	OpponentBases
+	CurrentGambits
 )
 
 type CountersPointer int
@@ -70,10 +75,13 @@ const (
 const NEEDLE_SUFFIX string = "_needle"
 const NEEDLE_ID string = "stealthNeedle_1"
 
-func newMiddleware(deck *map[string]*CardEntry) *Middleware {
+func newMiddleware(deck *map[string]*CardEntry, options GameOptions) *Middleware {
 	return &Middleware{
 		deck:      deck,
+		options:   options,
+		challenge: getChallenges()[options.Challenge],
 		allyState: emptyAllyState(),
+		logger:    slog.Default(),
 	}
 }
 
@@ -99,24 +107,24 @@ func (m *Middleware) resetAllyState() {
 }
 
 func (m *Middleware) activateAbility(ability *Ability, cardId string, player PlayerId, state *State, actions *[]StateAction) {
-	currentPlayer, err := playerByPointer(player, Current)
+	currentPlayer, err := playerByPointer(player, Current, state)
 	if err != nil {
 		// TODO handle error
-		log.Println(err)
+		m.logger.Warn("ability not activated", "err", err)
 		return
 	}
-	opponent, err := playerByPointer(player, Opponent)
+	opponent, err := playerByPointer(player, Opponent, state)
 	if err != nil {
 		// TODO handle error
-		log.Println(err)
+		m.logger.Warn("ability not activated", "err", err)
 		return
 	}
 
 	var abilityActions []StateAction
 	if ability.player == Current {
-		abilityActions = ability.actions(currentPlayer, cardId, state)
+		abilityActions = ability.actions(currentPlayer, cardId, state, m.logger)
 	} else {
-		abilityActions = ability.actions(opponent, cardId, state)
+		abilityActions = ability.actions(opponent, cardId, state, m.logger)
 	}
 	for _, action := range abilityActions {
 		*actions = append(*actions, action)
@@ -138,87 +146,117 @@ func (m *Middleware) processAbility(ability *Ability, cardId string, player Play
 	}
 }
 
-func (m *Middleware) handle(action string, player PlayerId, state *State) []StateAction {
+// safeHandle handles the message of the player, a panic is recovered and
+// the message is refused with no actions
+func (m *Middleware) safeHandle(message string, player PlayerId, state *State) (actions []StateAction, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			actions, err = nil, &PanicError{message, r, debug.Stack()}
+		}
+	}()
+	return m.handle(message, player, state)
+}
+
+func (m *Middleware) handle(action string, player PlayerId, state *State) ([]StateAction, error) {
+	deck := *m.deck
+	parsed := strings.Split(action, ",")
+	parsedAction, err := strconv.Atoi(parsed[0])
+	if err != nil {
+		return nil, &WrongMoveError{fmt.Sprintf("unknown action %q", parsed[0])}
+	}
+	userAction := UserAction(parsedAction)
+	// Nothing is changed by a refused move, the replays of the game only
+	// have the accepted ones
+	err = checkMove(userAction, parsed, player, state, deck)
+	if err != nil {
+		return nil, err
+	}
+
 	var actions []StateAction
 	var deferredActions []StateAction
 	actions = append(actions, &StateActionResetActions{})
 
-	currentPlayer, err := playerByPointer(player, Current)
+	currentPlayer, err := playerByPointer(player, Current, state)
 	if err != nil {
 		// TODO handle error
-		log.Println(err)
-		return actions
+		m.logger.Warn("message not handled", "err", err)
+		return actions, nil
 	}
 	currentPlayerCounters, err := countersByPointer(player, CurrentPlayerCounters, state)
 	if err != nil {
 		// TODO handle error
-		log.Println(err)
-		return actions
+		m.logger.Warn("message not handled", "err", err)
+		return actions, nil
 	}
 	currentPlayerActionRequest, err := actionRequestByPointer(player, CurrentPlayerActionRequest, state)
 	if err != nil {
 		// TODO handle error
-		log.Println(err)
-		return actions
+		m.logger.Warn("message not handled", "err", err)
+		return actions, nil
 	}
-	opponent, err := playerByPointer(player, Opponent)
+	opponent, err := playerByPointer(player, Opponent, state)
 	if err != nil {
 		// TODO handle error
-		log.Println(err)
-		return actions
+		m.logger.Warn("message not handled", "err", err)
+		return actions, nil
 	}
 
 	currentDeck, err := locationByPointer(CurrentDeck, player)
 	if err != nil {
 		// TODO: handle exception
-		log.Println(err)
-		return actions
+		m.logger.Warn("message not handled", "err", err)
+		return actions, nil
 	}
 	currentHand, err := locationByPointer(CurrentHand, player)
 	if err != nil {
 		// TODO: handle exception
-		log.Println(err)
-		return actions
+		m.logger.Warn("message not handled", "err", err)
+		return actions, nil
 	}
 	currentTable, err := locationByPointer(CurrentTable, player)
 	if err != nil {
 		// TODO: handle exception
-		log.Println(err)
-		return actions
+		m.logger.Warn("message not handled", "err", err)
+		return actions, nil
 	}
 	currentBases, err := locationByPointer(CurrentBases, player)
 	if err != nil {
 		// TODO: handle exception
-		log.Println(err)
-		return actions
+		m.logger.Warn("message not handled", "err", err)
+		return actions, nil
 	}
 	currentDiscard, err := locationByPointer(CurrentDiscard, player)
 	if err != nil {
 		// TODO: handle exception
-		log.Println(err)
-		return actions
+		m.logger.Warn("message not handled", "err", err)
+		return actions, nil
 	}
-	opponentDiscard, err := locationByPointer(OpponentDiscard, player)
+	currentGambits, err := locationByPointer(CurrentGambits, player)
 	if err != nil {
 		// TODO: handle exception
-		log.Println(err)
-		return actions
+		m.logger.Warn("message not handled", "err", err)
+		return actions, nil
 	}
 
-	deck := *m.deck
-	parsed := strings.Split(action, ",")
-	if len(parsed) == 0 {
-		//TODO: handle exception
-		return actions
-	}
-	parsedAction, err := strconv.Atoi(parsed[0])
-	if err != nil {
-		//TODO: handle exception
-		log.Println(err)
-		return actions
+	switch userAction {
+	case Undo:
+		err = m.undo(player, state, &actions)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, &StateActionGetState{})
+		return actions, nil
+	case DisableUndo:
+		actions = append(actions, &StateActionDisableUndo{player: player})
+		m.log(LogEntry{
+			Player: player,
+			Action: userAction,
+			Text:   fmt.Sprintf("%s turned undo off for the others", m.playerName(player)),
+		}, &actions)
+		actions = append(actions, &StateActionGetState{})
+		return actions, nil
 	}
-
-	userAction := UserAction(parsedAction)
+	checkpoint := m.checkpoint(player, state)
 
 	if m.deferredCall != nil {
 		deferredActions = m.deferredCall()
@@ -228,9 +266,13 @@ func (m *Middleware) handle(action string, player PlayerId, state *State) []Stat
 	case Play:
 		if len(parsed) < 2 {
 			//TODO: handle exception
-			return actions
+			return actions, nil
 		}
 		id := parsed[1]
+		if _, ok := state.Cards[id]; !ok {
+			//TODO: handle exception
+			return actions, nil
+		}
 		card, ok := deck[strings.Split(id, "_")[0]]
 		if ok {
 			if card.cardType == Ship {
@@ -263,15 +305,19 @@ func (m *Middleware) handle(action string, player PlayerId, state *State) []Stat
 	case ActivateAbility:
 		if len(parsed) < 3 {
 			//TODO: handle exception
-			return actions
+			return actions, nil
 		}
 		id := parsed[1]
+		if _, ok := state.Cards[strings.TrimSuffix(id, NEEDLE_SUFFIX)]; !ok {
+			//TODO: handle exception
+			return actions, nil
+		}
 		card, ok := deck[strings.Split(id, "_")[0]]
 		if ok {
 			parsedAbilityId, err := strconv.Atoi(parsed[2])
 			if err != nil {
 				//TODO: handle exception
-				return actions
+				return actions, nil
 			}
 
 			abilityId := AbilityId(parsedAbilityId)
@@ -287,10 +333,10 @@ func (m *Middleware) handle(action string, player PlayerId, state *State) []Stat
 						}
 						// Update AllyState after utilization
 						foundSameFactionCard := false
-						for cardId, c := range state.Cards {
+						inPlay := append(state.pile(currentTable), state.pile(currentBases)...)
+						for _, cardId := range inPlay {
 							cardEntryId := strings.Split(cardId, "_")[0]
 							if cardId != id &&
-								(c.Location == currentTable || c.Location == currentBases) &&
 								(deck[cardEntryId].faction == card.faction || cardEntryId == "mechWorld") {
 
 								foundSameFactionCard = true
@@ -314,50 +360,73 @@ func (m *Middleware) handle(action string, player PlayerId, state *State) []Stat
 
 	case End:
 		m.resetAllyState()
-		m.moveAll(currentTable, currentDiscard, &actions)
+		// In the Hydra format the turn is shared and ends for the whole team
+		for _, seat := range turnPlayers(state, player) {
+			m.moveAll(seatLocations[seat].Table, seatLocations[seat].Discard, &actions)
+		}
 		m.changeCounterValue(currentPlayer, Set, Trade, 0, &actions)
 		m.changeCounterValue(currentPlayer, Set, Combat, 0, &actions)
 		m.changeCounterValue(currentPlayer, Set, ShipsOnTop, 0, &actions)
 		m.changeCounterValue(currentPlayer, Set, fleetFlag, 0, &actions)
 		m.changeCounterValue(currentPlayer, Set, blobs, 0, &actions)
-		for i := 1; i <= HandCardsQty; i++ {
-			m.topCard(currentDeck, currentHand, &actions)
-		}
-		opponentCounters, err := countersByPointer(player, OpponentCounters, state)
-		if err != nil {
-			// TODO handle exception
-			return actions
+		for _, seat := range turnPlayers(state, player) {
+			for i := 1; i <= m.options.Setup.HandSize; i++ {
+				m.topCard(seatLocations[seat].Deck, seatLocations[seat].Hand, &actions)
+			}
 		}
-		if opponentCounters.Discard > 0 {
-			m.requestUserAction(opponent, DiscardCard, &actions)
-		} else {
-			m.requestUserAction(opponent, Start, &actions)
+		for i, next := range turnPlayers(state, nextTurn(state, player)) {
+			// The challenge plays its turn right away and is never asked
+			if m.challenge != nil {
+				break
+			}
+			// Teammates share the discard counter so only one of them discards
+			if i == 0 && state.counters(next).Discard > 0 {
+				m.requestUserAction(next, DiscardCard, &actions)
+			} else {
+				m.requestUserAction(next, Start, &actions)
+			}
 		}
 		actions = append(actions, &StateActionResetActivatedAbilities{})
 		actions = append(actions, &StateActionChangeTurn{})
+		if m.challenge != nil {
+			m.challengeTurn(m.challenge, player, state, &actions)
+		}
 	case Damage:
 		if len(parsed) < 2 {
 			//TODO: handle exception
-			return actions
+			return actions, nil
 		}
 		damage, err := strconv.Atoi(parsed[1])
 		if err != nil {
 			//TODO: handle exception
-			return actions
+			return actions, nil
+		}
+		target := opponent
+		if len(parsed) > 2 {
+			parsedTarget, err := strconv.Atoi(parsed[2])
+			if err != nil {
+				//TODO: handle exception
+				return actions, nil
+			}
+			target = PlayerId(parsedTarget)
+		}
+		if !canAttack(state, currentPlayer, target) {
+			//TODO: handle exception
+			return actions, nil
 		}
-		m.changeCounterValue(opponent, Decrease, Authority, damage, &actions)
 		m.changeCounterValue(currentPlayer, Decrease, Combat, damage, &actions)
+		m.damage(target, damage, state, &actions)
 	case Buy:
 		if len(parsed) < 2 {
 			//TODO: handle exception
-			return actions
+			return actions, nil
 		}
 		id := parsed[1]
 		cardEntryId := strings.Split(id, "_")[0]
 		card, ok := deck[cardEntryId]
-		if !ok {
+		if _, known := state.Cards[id]; !ok || !known {
 			//TODO: handle exception
-			return actions
+			return actions, nil
 		}
 
 		if card.cardType == Ship && currentPlayerCounters.ShipsOnTop > 0 {
@@ -371,10 +440,8 @@ func (m *Middleware) handle(action string, player PlayerId, state *State) []Stat
 			m.topCard(TradeDeck, TradeRow, &actions)
 		}
 	case Start:
-		for cardId, card := range state.Cards {
-			if card.Location == currentBases {
-				m.playAbilities(player, cardId, state, &actions)
-			}
+		for _, cardId := range append(state.pile(currentBases), state.pile(currentGambits)...) {
+			m.playAbilities(player, cardId, state, &actions)
 		}
 		actionRequested := false
 		for _, action := range actions {
@@ -388,38 +455,43 @@ func (m *Middleware) handle(action string, player PlayerId, state *State) []Stat
 	case DestroyBase:
 		if len(parsed) < 2 {
 			//TODO: handle exception
-			return actions
+			return actions, nil
 		}
 		baseId := parsed[1]
 		card, ok := deck[strings.Split(baseId, "_")[0]]
 		if !ok {
 			//TODO: handle exception
-			return actions
+			return actions, nil
 		}
-		if card.cardType == Ship {
+		if card.cardType == Ship || card.cardType == Gambit {
 			//TODO: handle exception
-			return actions
+			return actions, nil
+		}
+		owner, ok := attackedBaseOwner(baseId, player, state)
+		if !ok {
+			//TODO: handle exception
+			return actions, nil
 		}
 		m.changeCounterValue(currentPlayer, Decrease, Combat, card.defense, &actions)
-		m.moveCard(baseId, state.Cards[baseId].Location, opponentDiscard, &actions)
+		m.moveCard(baseId, state.Cards[baseId].Location, seatLocations[owner].Discard, &actions)
 	case DiscardCard:
 		if len(parsed) < 2 {
 			//TODO: handle exception
-			return actions
+			return actions, nil
 		}
 		id := parsed[1]
-		_, ok := deck[strings.Split(id, "_")[0]]
+		card, ok := state.Cards[id]
 		if !ok {
 			//TODO: handle exception
-			return actions
+			return actions, nil
 		}
 
-		m.moveCard(id, state.Cards[id].Location, currentDiscard, &actions)
+		m.moveCard(id, card.Location, currentDiscard, &actions)
 		m.changeCounterValue(currentPlayer, Decrease, Discard, 1, &actions)
 
 		if err != nil {
 			// TODO handle exception
-			return actions
+			return actions, nil
 		}
 		if currentPlayerCounters.Discard == 1 {
 			m.requestUserAction(currentPlayer, Start, &actions)
@@ -427,20 +499,20 @@ func (m *Middleware) handle(action string, player PlayerId, state *State) []Stat
 	case ScrapCard:
 		if len(parsed) > 1 {
 			id := parsed[1]
-			_, ok := deck[strings.Split(id, "_")[0]]
+			card, ok := state.Cards[id]
 			if ok {
-				m.moveCard(id, state.Cards[id].Location, ScrapHeap, &actions)
+				m.moveCard(id, card.Location, ScrapHeap, &actions)
 			}
 		}
 		m.requestUserAction(player, NoneAction, &actions)
 	case ScrapCardTradeRow:
 		if len(parsed) > 1 {
 			id := parsed[1]
-			_, ok := deck[strings.Split(id, "_")[0]]
+			card, ok := state.Cards[id]
 			if ok {
-				m.moveCard(id, state.Cards[id].Location, ScrapHeap, &actions)
+				m.moveCard(id, card.Location, ScrapHeap, &actions)
+				m.topCard(TradeDeck, TradeRow, &actions)
 			}
-			m.topCard(TradeDeck, TradeRow, &actions)
 		}
 		m.requestUserAction(player, NoneAction, &actions)
 	case ScrapCardInHand:
@@ -461,13 +533,18 @@ func (m *Middleware) handle(action string, player PlayerId, state *State) []Stat
 			card, ok := deck[strings.Split(baseId, "_")[0]]
 			if !ok {
 				//TODO: handle exception
-				return actions
+				return actions, nil
 			}
-			if card.cardType == Ship {
+			if card.cardType == Ship || card.cardType == Gambit {
 				//TODO: handle exception
-				return actions
+				return actions, nil
 			}
-			m.moveCard(baseId, state.Cards[baseId].Location, opponentDiscard, &actions)
+			owner, ok := attackedBaseOwner(baseId, player, state)
+			if !ok {
+				//TODO: handle exception
+				return actions, nil
+			}
+			m.moveCard(baseId, state.Cards[baseId].Location, seatLocations[owner].Discard, &actions)
 		}
 		m.requestUserAction(player, NoneAction, &actions)
 	case DestroyBaseBlobDestroyer:
@@ -476,30 +553,35 @@ func (m *Middleware) handle(action string, player PlayerId, state *State) []Stat
 			card, ok := deck[strings.Split(baseId, "_")[0]]
 			if !ok {
 				//TODO: handle exception
-				return actions
+				return actions, nil
 			}
-			if card.cardType == Ship {
+			if card.cardType == Ship || card.cardType == Gambit {
 				//TODO: handle exception
-				return actions
+				return actions, nil
+			}
+			owner, ok := attackedBaseOwner(baseId, player, state)
+			if !ok {
+				//TODO: handle exception
+				return actions, nil
 			}
-			m.moveCard(baseId, state.Cards[baseId].Location, opponentDiscard, &actions)
+			m.moveCard(baseId, state.Cards[baseId].Location, seatLocations[owner].Discard, &actions)
 		}
 		m.requestUserAction(player, ScrapCardTradeRow, &actions)
 	case AcquireShipForFree:
 		if len(parsed) < 2 {
 			//TODO: handle exception
-			return actions
+			return actions, nil
 		}
 		id := parsed[1]
 		cardEntryId := strings.Split(id, "_")[0]
 		card, ok := deck[cardEntryId]
 		if !ok {
 			//TODO: handle exception
-			return actions
+			return actions, nil
 		}
-		if card.cardType != Ship {
+		if _, known := state.Cards[id]; card.cardType != Ship || !known {
 			//TODO: handle exception
-			return actions
+			return actions, nil
 		}
 		m.moveCard(id, state.Cards[id].Location, currentDeck, &actions)
 		if cardEntryId != "explorer" {
@@ -554,27 +636,27 @@ func (m *Middleware) handle(action string, player PlayerId, state *State) []Stat
 	case ActivateNeedle:
 		if len(parsed) < 2 {
 			//TODO: handle exception
-			return actions
+			return actions, nil
 		}
 		id := parsed[1]
 		card, ok := state.Cards[id]
 		if !ok {
 			//TODO: handle exception
-			return actions
+			return actions, nil
 		}
 		if card.Location != currentTable {
 			//TODO: handle exception
-			return actions
+			return actions, nil
 		}
 		cardEntryId := strings.Split(id, "_")[0]
 		cardEntry, ok := deck[cardEntryId]
 		if !ok {
 			//TODO: handle exception
-			return actions
+			return actions, nil
 		}
 		if cardEntry.cardType != Ship {
 			//TODO: handle exception
-			return actions
+			return actions, nil
 		}
 		if len(cardEntry.beforePlay) > 0 {
 			for _, ability := range cardEntry.beforePlay {
@@ -600,28 +682,54 @@ func (m *Middleware) handle(action string, player PlayerId, state *State) []Stat
 		}
 	}
 
+	// The entry goes first, the actions might have added the entries of
+	// their own consequences such as eliminations. The deferred actions
+	// belong to the card played before.
+	entry := m.narrate(userAction, player, parsed, currentPlayerActionRequest, state, actions)
+	if entry != nil {
+		actions = append(actions[:1], append([]StateAction{&StateActionAddLogEntry{entry: *entry}}, actions[1:]...)...)
+	}
 	for _, action := range deferredActions {
 		actions = append(actions, action)
 	}
 
+	if revealsHiddenInformation(actions) {
+		m.lastCheckpoint = nil
+	} else {
+		m.lastCheckpoint = checkpoint
+	}
+
 	actions = append(actions, &StateActionGetState{})
-	return actions
+	return actions, nil
 }
 
 func (m *Middleware) prepareState() []StateAction {
 	var actions []StateAction
 	m.shuffle(TradeDeck, &actions)
-	m.shuffle(FirstPlayerDeck, &actions)
-	m.shuffle(SecondPlayerDeck, &actions)
-	for i := 1; i <= FirstPlayerHandCardsQty; i++ {
-		m.topCard(FirstPlayerDeck, FirstPlayerHand, &actions)
-	}
-	for i := 1; i <= HandCardsQty; i++ {
-		m.topCard(SecondPlayerDeck, SecondPlayerHand, &actions)
+	for i := 1; i <= m.options.Players; i++ {
+		locations := seatLocations[PlayerId(i)]
+		m.shuffle(locations.Deck, &actions)
+		for j := 1; j <= m.options.Setup.HandSizes[i-1]; j++ {
+			m.topCard(locations.Deck, locations.Hand, &actions)
+		}
 	}
-	for i := 1; i <= TradeRowQty; i++ {
+	for i := 1; i <= m.options.Setup.TradeRowSize; i++ {
 		m.topCard(TradeDeck, TradeRow, &actions)
 	}
+	if m.options.Gambits {
+		m.shuffle(GambitDeck, &actions)
+		for i := 1; i <= GambitsQty; i++ {
+			for j := 1; j <= m.options.Players; j++ {
+				m.topCard(GambitDeck, seatLocations[PlayerId(j)].Gambits, &actions)
+			}
+		}
+		// Gambits in play are activated on the turn start like bases,
+		// so the first turn has to be started explicitly
+		m.requestUserAction(m.options.Setup.FirstPlayer, Start, &actions)
+	}
+	if m.challenge != nil {
+		m.changeCounterValue(ChallengeSeat, Set, Authority, m.challenge.Authority, &actions)
+	}
 	actions = append(actions, &StateActionResetActions{})
 	return actions
 }
@@ -659,6 +767,55 @@ func (m *Middleware) playAbilities(player PlayerId, cardId string, state *State,
 	}
 }
 
+// damage decreases the authority of the target and eliminates it when the
+// authority runs out. The last player standing wins, in the team formats the
+// team loses together with the shared authority or with its emperor
+func (m *Middleware) damage(target PlayerId, value int, state *State, actions *[]StateAction) {
+	m.changeCounterValue(target, Decrease, Authority, value, actions)
+	if state.counters(target).Authority-value > 0 {
+		return
+	}
+	*actions = append(*actions, &StateActionEliminatePlayer{
+		player: target,
+	})
+	m.log(LogEntry{
+		Player: target,
+		Text:   fmt.Sprintf("%s was eliminated", m.playerName(target)),
+	}, actions)
+	switch state.Format {
+	case Hydra, Emperor:
+		if state.Format == Emperor && !isEmperor(state, target) {
+			return
+		}
+		team := FirstTeam
+		if teamOf(state, target) == FirstTeam {
+			team = SecondTeam
+		}
+		m.gameOver(teamLead(team), team, actions)
+	default:
+		remaining := []PlayerId{}
+		for _, player := range activePlayers(state) {
+			if player != target {
+				remaining = append(remaining, player)
+			}
+		}
+		if len(remaining) == 1 {
+			m.gameOver(remaining[0], NoTeam, actions)
+		}
+	}
+}
+
+func (m *Middleware) gameOver(winner PlayerId, team Team, actions *[]StateAction) {
+	*actions = append(*actions, &StateActionGameOver{
+		winner: winner,
+		team:   team,
+	})
+	m.log(LogEntry{
+		Player: winner,
+		Text:   fmt.Sprintf("%s won the game", m.playerName(winner)),
+	}, actions)
+}
+
 func (m *Middleware) moveCard(id string, from CardLocation, to CardLocation, actions *[]StateAction) {
 	*actions = append(*actions, &StateActionMoveCard{
 		id:   id,
@@ -743,118 +900,81 @@ func (e *WrongLocationPointerError) Error() string {
 	return fmt.Sprintf("wrong LocationPointer %d", e.p)
 }
 
-func playerByPointer(actualPlayer PlayerId, playerPointer PlayerPointer) (PlayerId, error) {
-	switch actualPlayer {
-	case FirstPlayer:
-		switch playerPointer {
-		case Current:
-			return FirstPlayer, nil
-		case Opponent:
-			return SecondPlayer, nil
-		default:
-			return actualPlayer, &WrongPlayerPointerError{playerPointer}
-		}
-	case SecondPlayer:
-		switch playerPointer {
-		case Current:
-			return SecondPlayer, nil
-		case Opponent:
-			return FirstPlayer, nil
-		default:
-			return actualPlayer, &WrongPlayerPointerError{playerPointer}
-		}
-	default:
+func playerByPointer(actualPlayer PlayerId, playerPointer PlayerPointer, state *State) (PlayerId, error) {
+	if !isSeated(state, actualPlayer) {
 		return actualPlayer, &WrongPlayerIdError{actualPlayer}
 	}
+	switch playerPointer {
+	case Current:
+		return actualPlayer, nil
+	case Opponent:
+		return defaultTarget(state, actualPlayer), nil
+	default:
+		return actualPlayer, &WrongPlayerPointerError{playerPointer}
+	}
 }
 
 func locationByPointer(pointer LocationPointer, player PlayerId) (CardLocation, error) {
-	switch player {
-	case FirstPlayer:
-		switch pointer {
-		case CurrentTable:
-			return FirstPlayerTable, nil
-		case CurrentBases:
-			return FirstPlayerBases, nil
-		case CurrentDeck:
-			return FirstPlayerDeck, nil
-		case CurrentHand:
-			return FirstPlayerHand, nil
-		case CurrentDiscard:
-			return FirstPlayerDiscard, nil
-		case OpponentDiscard:
-			return SecondPlayerDiscard, nil
-		default:
-			return UndefinedLocation, &WrongLocationPointerError{pointer}
-		}
-	case SecondPlayer:
-		switch pointer {
-		case CurrentTable:
-			return SecondPlayerTable, nil
-		case CurrentBases:
-			return SecondPlayerBases, nil
-		case CurrentDeck:
-			return SecondPlayerDeck, nil
-		case CurrentHand:
-			return SecondPlayerHand, nil
-		case CurrentDiscard:
-			return SecondPlayerDiscard, nil
-		case OpponentDiscard:
-			return FirstPlayerDiscard, nil
-		default:
-			return UndefinedLocation, &WrongLocationPointerError{pointer}
-		}
-	default:
+	locations, ok := seatLocations[player]
+	if !ok {
 		return UndefinedLocation, &WrongPlayerIdError{player}
 	}
+	switch pointer {
+	case CurrentTable:
+		return locations.Table, nil
+	case CurrentBases:
+		return locations.Bases, nil
+	case CurrentDeck:
+		return locations.Deck, nil
+	case CurrentHand:
+		return locations.Hand, nil
+	case CurrentDiscard:
+		return locations.Discard, nil
+	case CurrentGambits:
+		return locations.Gambits, nil
+	default:
+		return UndefinedLocation, &WrongLocationPointerError{pointer}
+	}
 }
 
 func countersByPointer(player PlayerId, countersPointer CountersPointer, state *State) (Counters, error) {
-	switch player {
-	case FirstPlayer:
-		switch countersPointer {
-		case CurrentPlayerCounters:
-			return state.FirstPlayerCounters, nil
-		case OpponentCounters:
-			return state.SecondPlayerCounters, nil
-		default:
-			return Counters{}, &WrongCountersPointerError{countersPointer}
-		}
-	case SecondPlayer:
-		switch countersPointer {
-		case CurrentPlayerCounters:
-			return state.SecondPlayerCounters, nil
-		case OpponentCounters:
-			return state.FirstPlayerCounters, nil
-		default:
-			return Counters{}, &WrongCountersPointerError{countersPointer}
-		}
-	default:
+	if !isSeated(state, player) {
 		return Counters{}, &WrongPlayerIdError{player}
 	}
+	switch countersPointer {
+	case CurrentPlayerCounters:
+		return *state.counters(player), nil
+	case OpponentCounters:
+		return *state.counters(defaultTarget(state, player)), nil
+	default:
+		return Counters{}, &WrongCountersPointerError{countersPointer}
+	}
 }
 
 func actionRequestByPointer(player PlayerId, pointer ActionRequestPointer, state *State) (ActionRequest, error) {
-	switch player {
-	case FirstPlayer:
-		switch pointer {
-		case CurrentPlayerActionRequest:
-			return state.FirstPlayerActionRequest, nil
-		case OpponentActionRequest:
-			return state.SecondPlayerActionRequest, nil
-		default:
-			return ActionRequest{}, &WrongActionRequestPointerError{pointer}
-		}
-	case SecondPlayer:
-		switch pointer {
-		case CurrentPlayerActionRequest:
-			return state.SecondPlayerActionRequest, nil
-		case OpponentActionRequest:
-			return state.FirstPlayerActionRequest, nil
-		default:
-			return ActionRequest{}, &WrongActionRequestPointerError{pointer}
-		}
-	default:
+	if !isSeated(state, player) {
 		return ActionRequest{}, &WrongPlayerIdError{player}
 	}
+	switch pointer {
+	case CurrentPlayerActionRequest:
+		return *state.actionRequest(player), nil
+	case OpponentActionRequest:
+		return *state.actionRequest(defaultTarget(state, player)), nil
+	default:
+		return ActionRequest{}, &WrongActionRequestPointerError{pointer}
+	}
+}
+
+// attackedBaseOwner returns the owner of the base if the player is allowed
+// to attack it
+func attackedBaseOwner(baseId string, player PlayerId, state *State) (PlayerId, bool) {
+	card, ok := state.Cards[baseId]
+	if !ok {
+		return 0, false
+	}
+	owner, ok := ownerOf(card.Location)
+	if !ok || seatLocations[owner].Bases != card.Location {
+		return 0, false
+	}
+	return owner, canAttack(state, player, owner)
 }
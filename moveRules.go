@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type WrongMoveError struct {
+	reason string
+}
+
+func (e *WrongMoveError) Error() string {
+	return fmt.Sprintf("wrong move: %s", e.reason)
+}
+
+// The user actions answering the action request of a card
+var requestAnswers = map[UserAction]bool{
+	ScrapCard:                true,
+	ScrapCardTradeRow:        true,
+	ScrapCardInHand:          true,
+	DestroyBaseForFree:       true,
+	DestroyBaseBlobDestroyer: true,
+	AcquireShipForFree:       true,
+	ActivateBrainWorld:       true,
+	ActivateRecyclingStation: true,
+	ActivateMechWorld:        true,
+	ActivateNeedle:           true,
+}
+
+// The user actions which name a card of the game
+var cardActions = map[UserAction]bool{
+	Play:               true,
+	ActivateAbility:    true,
+	Buy:                true,
+	DestroyBase:        true,
+	DiscardCard:        true,
+	AcquireShipForFree: true,
+	ActivateNeedle:     true,
+}
+
+// checkMove refuses the moves the rules don't allow and the malformed
+// messages before the middleware changes anything
+func checkMove(userAction UserAction, parsed []string, player PlayerId, state *State, deck map[string]*CardEntry) error {
+	if !isSeated(state, player) {
+		return &WrongMoveError{fmt.Sprintf("no seat %d", player)}
+	}
+	if userAction == DisableUndo {
+		for _, seat := range state.UndoRefused {
+			if seat == player {
+				return &WrongMoveError{"undo is turned off already"}
+			}
+		}
+		// Undo is turned off at any time, not only in the own turns
+		return nil
+	}
+	if !holdsTurn(state, player) {
+		return &WrongMoveError{fmt.Sprintf("the turn belongs to player %d", state.Turn)}
+	}
+	if requestAnswers[userAction] && state.actionRequest(player).Action != userAction {
+		return &WrongMoveError{"the action wasn't requested"}
+	}
+	if cardActions[userAction] && len(parsed) < 2 {
+		return &WrongMoveError{"no card is given"}
+	}
+	counters := state.counters(player)
+	locations := seatLocations[player]
+	var card *Card
+	var entry *CardEntry
+	if cardActions[userAction] {
+		id := strings.TrimSuffix(parsed[1], NEEDLE_SUFFIX)
+		var known, ok bool
+		card, known = state.Cards[id]
+		entry, ok = deck[strings.Split(id, "_")[0]]
+		if !known || !ok {
+			return &WrongMoveError{fmt.Sprintf("unknown card %s", parsed[1])}
+		}
+	}
+	switch userAction {
+	case Play:
+		if card.Location != locations.Hand {
+			return &WrongMoveError{"only the cards in the hand are played"}
+		}
+	case ActivateAbility:
+		if len(parsed) < 3 {
+			return &WrongMoveError{"no ability is given"}
+		}
+		ability, err := strconv.Atoi(parsed[2])
+		if err != nil {
+			return &WrongMoveError{fmt.Sprintf("unknown ability %s", parsed[2])}
+		}
+		if owner, ok := ownerOf(card.Location); !ok || owner != player {
+			return &WrongMoveError{"only the abilities of the own cards are activated"}
+		}
+		if !state.ActivatedAbilities[parsed[1]][AbilityId(ability)] {
+			return &WrongMoveError{fmt.Sprintf("ability %d of %s isn't available", ability, parsed[1])}
+		}
+	case Damage:
+		if len(parsed) < 2 {
+			return &WrongMoveError{"no damage is given"}
+		}
+		damage, err := strconv.Atoi(parsed[1])
+		if err != nil {
+			return &WrongMoveError{fmt.Sprintf("damage %s isn't a number", parsed[1])}
+		}
+		if damage <= 0 || damage > counters.Combat {
+			return &WrongMoveError{fmt.Sprintf("damage %d with %d combat", damage, counters.Combat)}
+		}
+		target := defaultTarget(state, player)
+		if len(parsed) > 2 {
+			parsedTarget, err := strconv.Atoi(parsed[2])
+			if err != nil {
+				return &WrongMoveError{fmt.Sprintf("target %s isn't a number", parsed[2])}
+			}
+			target = PlayerId(parsedTarget)
+		}
+		if !canAttack(state, player, target) {
+			return &WrongMoveError{fmt.Sprintf("player %d can't be attacked", target)}
+		}
+	case Buy:
+		if !inMarket(card) {
+			return &WrongMoveError{"only the trade row and the explorers are for sale"}
+		}
+		if entry.cost > counters.Trade {
+			return &WrongMoveError{fmt.Sprintf("cost %d with %d trade", entry.cost, counters.Trade)}
+		}
+	case AcquireShipForFree:
+		if !inMarket(card) || entry.cardType != Ship {
+			return &WrongMoveError{"only the ships of the trade row and the explorers are acquired"}
+		}
+	case DestroyBase:
+		if _, ok := attackedBaseOwner(parsed[1], player, state); !ok {
+			return &WrongMoveError{"only the bases of the attacked players are destroyed"}
+		}
+		if entry.defense > counters.Combat {
+			return &WrongMoveError{fmt.Sprintf("defense %d with %d combat", entry.defense, counters.Combat)}
+		}
+	case DiscardCard:
+		if counters.Discard <= 0 {
+			return &WrongMoveError{"no card has to be discarded"}
+		}
+		if card.Location != locations.Hand {
+			return &WrongMoveError{"only the cards in the hand are discarded"}
+		}
+	case ActivateNeedle:
+		if card.Location != locations.Table || entry.cardType != Ship {
+			return &WrongMoveError{"only the ships in play are copied"}
+		}
+	case ScrapCard:
+		if len(parsed) > 1 {
+			card, known := state.Cards[parsed[1]]
+			if known && card.Location != locations.Hand && card.Location != locations.Discard {
+				return &WrongMoveError{"only the cards in the hand or the discard pile are scrapped"}
+			}
+		}
+	case ScrapCardTradeRow:
+		if len(parsed) > 1 {
+			card, known := state.Cards[parsed[1]]
+			if known && card.Location != TradeRow {
+				return &WrongMoveError{"only the cards in the trade row are scrapped"}
+			}
+		}
+	}
+	return nil
+}
+
+// holdsTurn tells if the player acts in the current turn, the teammates
+// share the turn in the Hydra format
+func holdsTurn(state *State, player PlayerId) bool {
+	for _, seat := range turnPlayers(state, state.Turn) {
+		if seat == player {
+			return true
+		}
+	}
+	return false
+}
+
+// inMarket tells if the card can be acquired, from the trade row or from the
+// explorers
+func inMarket(card *Card) bool {
+	return card.Location == TradeRow || card.Location == Explorers
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A game is written as a header of "Key: value" lines, a blank line and
+// then one action per line:
+//
+//	Seed: 1234
+//	Players: 2
+//	Format: free-for-all
+//	Gambits: false
+//	Pool: Barter World x2; Battle Blob x1; ...
+//	Setup: {"authority":[50,50],"handSizes":[3,5],...}
+//
+//	1: Play Scout#3, Viper#1
+//	1: ActivateAbility Blob Fighter#2, 0
+//	2: Damage 5, 1
+//
+// The cards are written by their names and copy numbers so the notation
+// doesn't depend on the internal ids.
+
+// RecordedAction is a user action exactly as it was handled by the hub
+type RecordedAction struct {
+	player  PlayerId
+	message string
+}
+
+var userActionNames = map[UserAction]string{
+	NoneAction:               "None",
+	Play:                     "Play",
+	End:                      "End",
+	Damage:                   "Damage",
+	Buy:                      "Buy",
+	Utilize:                  "Utilize",
+	Start:                    "Start",
+	DestroyBase:              "DestroyBase",
+	DiscardCard:              "DiscardCard",
+	ActivateAbility:          "ActivateAbility",
+	ScrapCard:                "ScrapCard",
+	ScrapCardTradeRow:        "ScrapCardTradeRow",
+	ScrapCardInHand:          "ScrapCardInHand",
+	DestroyBaseForFree:       "DestroyBaseForFree",
+	AcquireShipForFree:       "AcquireShipForFree",
+	DestroyBaseBlobDestroyer: "DestroyBaseBlobDestroyer",
+	ActivateBrainWorld:       "ActivateBrainWorld",
+	ActivateMechWorld:        "ActivateMechWorld",
+	ActivateRecyclingStation: "ActivateRecyclingStation",
+	ActivateNeedle:           "ActivateNeedle",
+	Undo:                     "Undo",
+	DisableUndo:              "DisableUndo",
+}
+
+var formatNames = map[GameFormat]string{
+	FreeForAll: "free-for-all",
+	Hunter:     "hunter",
+	Vulture:    "vulture",
+	Hydra:      "hydra",
+	Emperor:    "emperor",
+}
+
+type WrongNotationError struct {
+	line   int
+	reason string
+}
+
+func (e *WrongNotationError) Error() string {
+	return fmt.Sprintf("wrong notation at line %d: %s", e.line, e.reason)
+}
+
+// gameDeck is the card pool of a game with the options
+func gameDeck(options GameOptions) *map[string]*CardEntry {
+	deck := getDeck()
+	if options.Gambits {
+		addGambits(deck)
+	}
+	for key, qty := range options.Setup.CardPool {
+		if card, ok := (*deck)[key]; ok {
+			card.qty = qty
+		}
+	}
+	return deck
+}
+
+func poolNotation(deck *map[string]*CardEntry) string {
+	cards := make([]string, 0, len(*deck))
+	for _, card := range *deck {
+		cards = append(cards, fmt.Sprintf("%s x%d", card.name, card.qty))
+	}
+	sort.Strings(cards)
+	return strings.Join(cards, "; ")
+}
+
+func exportNotation(options GameOptions, history []RecordedAction) string {
+	deck := gameDeck(options)
+	var b strings.Builder
+	fmt.Fprintf(&b, "Seed: %d\n", options.Seed)
+	fmt.Fprintf(&b, "Players: %d\n", options.Players)
+	fmt.Fprintf(&b, "Format: %s\n", formatNames[options.Format])
+	fmt.Fprintf(&b, "Gambits: %t\n", options.Gambits)
+	if options.Challenge != "" {
+		fmt.Fprintf(&b, "Challenge: %s\n", options.Challenge)
+	}
+	if options.NoUndo {
+		fmt.Fprintf(&b, "NoUndo: %t\n", options.NoUndo)
+	}
+	fmt.Fprintf(&b, "Pool: %s\n", poolNotation(deck))
+	setup, err := json.Marshal(options.Setup)
+	if err != nil {
+		slog.Error("setup not encoded", "err", err)
+	}
+	fmt.Fprintf(&b, "Setup: %s\n", setup)
+	b.WriteString("\n")
+	for _, recorded := range history {
+		fmt.Fprintf(&b, "%d: %s\n", recorded.player, actionNotation(deck, recorded.message))
+	}
+	return b.String()
+}
+
+// actionNotation turns "1,blobFighter_2" into "Play Blob Fighter#2"
+func actionNotation(deck *map[string]*CardEntry, message string) string {
+	parsed := strings.Split(message, ",")
+	name := parsed[0]
+	if action, err := strconv.Atoi(parsed[0]); err == nil {
+		if actionName, ok := userActionNames[UserAction(action)]; ok {
+			name = actionName
+		}
+	}
+	args := make([]string, 0, len(parsed)-1)
+	for _, arg := range parsed[1:] {
+		args = append(args, cardNotation(deck, arg))
+	}
+	if len(args) == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s %s", name, strings.Join(args, ", "))
+}
+
+func cardNotation(deck *map[string]*CardEntry, id string) string {
+	parsed := strings.SplitN(id, "_", 2)
+	card, ok := (*deck)[parsed[0]]
+	if !ok || len(parsed) < 2 {
+		return id
+	}
+	return fmt.Sprintf("%s#%s", card.name, parsed[1])
+}
+
+// importNotation parses the notation back into the options and the actions
+// to replay, the card pool has to match the one of this server
+func importNotation(notation string) (GameOptions, []RecordedAction, error) {
+	options := GameOptions{}
+	history := []RecordedAction{}
+	pool := ""
+	header := true
+	scanner := bufio.NewScanner(strings.NewReader(notation))
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			header = false
+			continue
+		}
+		parsed := strings.SplitN(text, ":", 2)
+		if len(parsed) < 2 {
+			return options, nil, &WrongNotationError{line, "missing colon"}
+		}
+		key, value := strings.TrimSpace(parsed[0]), strings.TrimSpace(parsed[1])
+		if header {
+			err := parseHeader(&options, &pool, key, value)
+			if err != nil {
+				return options, nil, &WrongNotationError{line, err.Error()}
+			}
+			continue
+		}
+		player, err := strconv.Atoi(key)
+		if err != nil || player < 1 || player > MaxPlayers {
+			return options, nil, &WrongNotationError{line, fmt.Sprintf("wrong player %q", key)}
+		}
+		message, err := parseActionNotation(gameDeck(options), value)
+		if err != nil {
+			return options, nil, &WrongNotationError{line, err.Error()}
+		}
+		history = append(history, RecordedAction{
+			player:  PlayerId(player),
+			message: message,
+		})
+	}
+	err := scanner.Err()
+	if err != nil {
+		return options, nil, err
+	}
+	if pool != poolNotation(gameDeck(options)) {
+		return options, nil, &WrongNotationError{line, "the card pool differs from the one of this server"}
+	}
+	return options, history, nil
+}
+
+func parseHeader(options *GameOptions, pool *string, key string, value string) error {
+	var err error
+	switch key {
+	case "Seed":
+		options.Seed, err = strconv.ParseInt(value, 10, 64)
+	case "Players":
+		options.Players, err = strconv.Atoi(value)
+	case "Format":
+		found := false
+		for format, name := range formatNames {
+			if name == value {
+				options.Format = format
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown format %q", value)
+		}
+	case "Gambits":
+		options.Gambits, err = strconv.ParseBool(value)
+	case "Challenge":
+		options.Challenge = value
+	case "NoUndo":
+		options.NoUndo, err = strconv.ParseBool(value)
+	case "Pool":
+		*pool = value
+	case "Setup":
+		err = json.Unmarshal([]byte(value), &options.Setup)
+	default:
+		return fmt.Errorf("unknown header %q", key)
+	}
+	return err
+}
+
+func parseActionNotation(deck *map[string]*CardEntry, text string) (string, error) {
+	parsed := strings.SplitN(text, " ", 2)
+	message := parsed[0]
+	for action, name := range userActionNames {
+		if name == parsed[0] {
+			message = strconv.Itoa(int(action))
+		}
+	}
+	if _, err := strconv.Atoi(message); err != nil {
+		return "", fmt.Errorf("unknown action %q", parsed[0])
+	}
+	if len(parsed) < 2 {
+		return message, nil
+	}
+	keys := make(map[string]string)
+	for key, card := range *deck {
+		keys[card.name] = key
+	}
+	for _, arg := range strings.Split(parsed[1], ",") {
+		arg = strings.TrimSpace(arg)
+		card := strings.SplitN(arg, "#", 2)
+		if len(card) == 2 {
+			key, ok := keys[card[0]]
+			if !ok {
+				return "", fmt.Errorf("unknown card %q", card[0])
+			}
+			arg = fmt.Sprintf("%s_%s", key, card[1])
+		}
+		message += "," + arg
+	}
+	return message, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// PatchOperation is a JSON Patch (RFC 6902) operation
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// StateUpdate is sent to the clients after every step. Clients which have
+// the previous version get the patch, the rest get the snapshot.
+type StateUpdate struct {
+	Version  int
+	Snapshot []byte
+	Patch    []byte
+}
+
+type SnapshotMessage struct {
+	Version int         `json:"version"`
+	State   interface{} `json:"state"`
+}
+
+type PatchMessage struct {
+	Version int              `json:"version"`
+	Patch   []PatchOperation `json:"patch"`
+}
+
+// diff appends the operations turning the decoded json document a into b
+func diff(path string, a interface{}, b interface{}, operations *[]PatchOperation) {
+	switch a := a.(type) {
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		for _, key := range sortedKeys(a) {
+			if _, ok := b[key]; !ok {
+				*operations = append(*operations, PatchOperation{Op: "remove", Path: path + "/" + escapePointer(key)})
+			}
+		}
+		for _, key := range sortedKeys(b) {
+			value, ok := a[key]
+			if !ok {
+				*operations = append(*operations, PatchOperation{Op: "add", Path: path + "/" + escapePointer(key), Value: b[key]})
+				continue
+			}
+			diff(path+"/"+escapePointer(key), value, b[key], operations)
+		}
+		return
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok {
+			break
+		}
+		// Appending is the most common change of the lists, e.g. Actions
+		if len(a) <= len(b) && reflect.DeepEqual(a, b[:len(a)]) {
+			for _, value := range b[len(a):] {
+				*operations = append(*operations, PatchOperation{Op: "add", Path: path + "/-", Value: value})
+			}
+			return
+		}
+	}
+	if !reflect.DeepEqual(a, b) {
+		*operations = append(*operations, PatchOperation{Op: "replace", Path: path, Value: b})
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func escapePointer(key string) string {
+	key = strings.Replace(key, "~", "~0", -1)
+	return strings.Replace(key, "/", "~1", -1)
+}
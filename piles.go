@@ -0,0 +1,83 @@
+package main
+
+// Pile is the ordered card ids of a location, from the bottom to the top
+type Pile []string
+
+func (p Pile) top() (string, bool) {
+	if len(p) == 0 {
+		return "", false
+	}
+	return p[len(p)-1], true
+}
+
+// pile returns a copy of the cards of the location from the bottom to the
+// top
+func (s *State) pile(location CardLocation) Pile {
+	return append(Pile{}, s.piles[location]...)
+}
+
+// placeCard takes the card from its pile and puts it on top of the other
+func (s *State) placeCard(id string, to CardLocation) bool {
+	card, ok := s.Cards[id]
+	if !ok {
+		return false
+	}
+	s.takeCard(id, card)
+	index := len(s.piles[to]) + 1
+	if top, ok := s.piles[to].top(); ok && keepsSlots(to) {
+		index = s.Cards[top].Index + 1
+	}
+	s.piles[to] = append(s.piles[to], id)
+	card.Location = to
+	card.Index = index
+	return true
+}
+
+// keepsSlots tells the locations where the cards keep their indexes when a
+// card leaves, the clients show the trade row and the hands by the slots
+func keepsSlots(location CardLocation) bool {
+	return location == TradeRow || isHand(location)
+}
+
+func (s *State) takeCard(id string, card *Card) {
+	pile := s.piles[card.Location]
+	position := card.Index - 1
+	if position < 0 || position >= len(pile) || pile[position] != id {
+		// The index is the position except in the slots, they are searched
+		position = -1
+		for i, pileId := range pile {
+			if pileId == id {
+				position = i
+			}
+		}
+		if position < 0 {
+			return
+		}
+	}
+	pile = append(pile[:position], pile[position+1:]...)
+	s.piles[card.Location] = pile
+	if keepsSlots(card.Location) {
+		return
+	}
+	// The cards above move down, taking from the top costs nothing
+	for i := position; i < len(pile); i++ {
+		s.Cards[pile[i]].Index = i + 1
+	}
+}
+
+// setPile replaces the order of the cards of the location, the ids have to
+// be the same cards
+func (s *State) setPile(location CardLocation, ids Pile) {
+	s.piles[location] = ids
+	for i, id := range ids {
+		s.Cards[id].Index = i + 1
+	}
+}
+
+func (s *State) clonePiles() map[CardLocation]Pile {
+	piles := make(map[CardLocation]Pile)
+	for location, pile := range s.piles {
+		piles[location] = append(Pile{}, pile...)
+	}
+	return piles
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	InitialRating int = 1500
+	// The most rating a player may win or lose in a game
+	RatingK float64 = 32
+)
+
+const (
+	Win  = "win"
+	Loss = "loss"
+)
+
+type MatchRecord struct {
+	Hub       string   `json:"hub"`
+	Opponents []string `json:"opponents"`
+	Teammates []string `json:"teammates,omitempty"`
+	Result    string   `json:"result"`
+	// 1 for the winner, the eliminated players are placed in the reverse
+	// order of their elimination
+	Place int `json:"place"`
+	// Cards of every faction the player owned at the end of the game
+	Factions     map[Faction]int `json:"factions"`
+	Duration     int64           `json:"duration"`
+	Finished     time.Time       `json:"finished"`
+	Rating       int             `json:"rating"`
+	RatingChange int             `json:"ratingChange"`
+}
+
+// MatchSeat is the result of one seat of a finished game
+type MatchSeat struct {
+	account  string
+	team     Team
+	place    int
+	factions map[Faction]int
+}
+
+type LeaderboardEntry struct {
+	Id     string `json:"id"`
+	Rating int    `json:"rating"`
+	Games  int    `json:"games"`
+	Wins   int    `json:"wins"`
+}
+
+// recordMatch updates the Elo ratings of the seats, every player is rated
+// against every opponent of another team as if they have played one on one
+func (s *Store) recordMatch(hub string, seats []MatchSeat, duration time.Duration) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	accounts := make([]*Account, len(seats))
+	for i, seat := range seats {
+		account, ok := s.data.Accounts[strings.ToLower(seat.account)]
+		if !ok {
+			return &WrongAccountError{"unknown account " + seat.account}
+		}
+		accounts[i] = account
+	}
+	opponents := func(i int, j int) bool {
+		return i != j && (seats[i].team == NoTeam || seats[i].team != seats[j].team)
+	}
+	changes := make([]int, len(seats))
+	for i := range seats {
+		score := 0.0
+		count := 0
+		for j := range seats {
+			if !opponents(i, j) {
+				continue
+			}
+			count++
+			expected := 1 / (1 + math.Pow(10, float64(accounts[j].Rating-accounts[i].Rating)/400))
+			actual := 0.5
+			if seats[i].place < seats[j].place {
+				actual = 1
+			} else if seats[i].place > seats[j].place {
+				actual = 0
+			}
+			score += actual - expected
+		}
+		if count > 0 {
+			changes[i] = int(math.Round(RatingK * score / float64(count)))
+		}
+	}
+	finished := time.Now()
+	for i, seat := range seats {
+		record := MatchRecord{
+			Hub:          hub,
+			Opponents:    []string{},
+			Result:       Loss,
+			Place:        seat.place,
+			Factions:     seat.factions,
+			Duration:     int64(duration.Seconds()),
+			Finished:     finished,
+			RatingChange: changes[i],
+		}
+		for j := range seats {
+			if opponents(i, j) {
+				record.Opponents = append(record.Opponents, accounts[j].Id)
+			} else if i != j {
+				record.Teammates = append(record.Teammates, accounts[j].Id)
+			}
+		}
+		if seat.place == 1 {
+			record.Result = Win
+		}
+		accounts[i].Rating += changes[i]
+		record.Rating = accounts[i].Rating
+		accounts[i].History = append(accounts[i].History, record)
+	}
+	return s.save()
+}
+
+func (s *Store) matchHistory(id string) ([]MatchRecord, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	account, ok := s.data.Accounts[strings.ToLower(id)]
+	if !ok {
+		return nil, false
+	}
+	return append([]MatchRecord{}, account.History...), true
+}
+
+// leaderboard lists the accounts which have played a rated game
+func (s *Store) leaderboard() []LeaderboardEntry {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	entries := []LeaderboardEntry{}
+	for _, account := range s.data.Accounts {
+		if len(account.History) == 0 {
+			continue
+		}
+		entry := LeaderboardEntry{
+			Id:     account.Id,
+			Rating: account.Rating,
+			Games:  len(account.History),
+		}
+		for _, record := range account.History {
+			if record.Result == Win {
+				entry.Wins++
+			}
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Rating != entries[j].Rating {
+			return entries[i].Rating > entries[j].Rating
+		}
+		return entries[i].Id < entries[j].Id
+	})
+	return entries
+}
+
+// factionMix counts the cards of every faction owned by the seat
+func factionMix(deck *map[string]*CardEntry, state *State, player PlayerId) map[Faction]int {
+	factions := make(map[Faction]int)
+	for id, card := range state.Cards {
+		owner, ok := ownerOf(card.Location)
+		if !ok || owner != player {
+			continue
+		}
+		entry, ok := (*deck)[strings.Split(id, "_")[0]]
+		if !ok || entry.cardType == Gambit {
+			continue
+		}
+		factions[entry.faction]++
+	}
+	return factions
+}
@@ -0,0 +1,1126 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// The decks are dealt unshuffled in the scenarios, the first starting deck
+// is scout_1 to scout_8 and viper_1, viper_2 and the second one is scout_9
+// to scout_16 and viper_3, viper_4
+
+var unalignedCases = []ruleCase{
+	{
+		name:  "scout gives trade",
+		piles: map[string][]string{"firstPlayerHand": {"scout_1"}},
+		steps: []ruleStep{play("scout_1")},
+		locations: map[string]string{
+			"scout_1": "firstPlayerTable",
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(1, 0, 50)},
+	},
+	{
+		name:  "viper gives combat",
+		piles: map[string][]string{"firstPlayerHand": {"viper_1"}},
+		steps: []ruleStep{play("viper_1")},
+		want:  map[PlayerId]Counters{FirstPlayer: counters(0, 1, 50)},
+	},
+	{
+		name:  "explorer is scrapped for combat",
+		piles: map[string][]string{"firstPlayerHand": {"explorer_1"}},
+		steps: []ruleStep{play("explorer_1"), activate("explorer_1", Utilization)},
+		locations: map[string]string{
+			"explorer_1": "scrapHeap",
+		},
+		want:      map[PlayerId]Counters{FirstPlayer: counters(2, 2, 50)},
+		abilities: map[string]ActivatedAbilities{"explorer_1": {Utilization: false}},
+	},
+}
+
+var blobCases = []ruleCase{
+	{
+		name: "blob fighter draws with an ally",
+		piles: map[string][]string{
+			"firstPlayerHand": {"blobFighter_1", "tradePod_1"},
+			"firstPlayerDeck": {"scout_1"},
+		},
+		steps: []ruleStep{play("blobFighter_1"), play("tradePod_1")},
+		locations: map[string]string{
+			"scout_1": "firstPlayerHand",
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(3, 5, 50)},
+	},
+	{
+		name: "blob fighter doesn't draw alone",
+		piles: map[string][]string{
+			"firstPlayerHand": {"blobFighter_1"},
+		},
+		steps: []ruleStep{play("blobFighter_1")},
+		sizes: map[string]int{"firstPlayerHand": 0},
+		want:  map[PlayerId]Counters{FirstPlayer: counters(0, 3, 50)},
+	},
+	{
+		name: "battle blob draws with an ally and is scrapped for combat",
+		piles: map[string][]string{
+			"firstPlayerHand": {"battleBlob_1", "blobFighter_1"},
+			"firstPlayerDeck": {"scout_2", "scout_1"},
+		},
+		steps: []ruleStep{play("battleBlob_1"), play("blobFighter_1"), activate("battleBlob_1", Utilization)},
+		locations: map[string]string{
+			"battleBlob_1": "scrapHeap",
+			"scout_1":      "firstPlayerHand",
+			"scout_2":      "firstPlayerHand",
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(0, 15, 50)},
+	},
+	{
+		name: "mothership draws and draws again with an ally",
+		piles: map[string][]string{
+			"firstPlayerHand": {"mothership_1", "blobFighter_1"},
+			"firstPlayerDeck": {"scout_3", "scout_2", "scout_1"},
+		},
+		steps: []ruleStep{play("mothership_1"), play("blobFighter_1")},
+		sizes: map[string]int{"firstPlayerHand": 3},
+		want:  map[PlayerId]Counters{FirstPlayer: counters(0, 9, 50)},
+	},
+	{
+		name: "ram is scrapped for trade",
+		piles: map[string][]string{
+			"firstPlayerHand": {"ram_1", "ram_2"},
+		},
+		steps: []ruleStep{play("ram_1"), play("ram_2"), activate("ram_1", Utilization)},
+		locations: map[string]string{
+			"ram_1": "scrapHeap",
+			"ram_2": "firstPlayerTable",
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(3, 14, 50)},
+	},
+	{
+		name: "the hive is an ally of the ships played after it",
+		piles: map[string][]string{
+			"firstPlayerHand": {"theHive_1", "blobFighter_1"},
+			"firstPlayerDeck": {"scout_2", "scout_1"},
+		},
+		steps: []ruleStep{play("theHive_1"), play("blobFighter_1")},
+		locations: map[string]string{
+			"theHive_1": "firstPlayerBases",
+			"scout_1":   "firstPlayerHand",
+			"scout_2":   "firstPlayerHand",
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(0, 6, 50)},
+	},
+	{
+		name: "blob wheel is scrapped for trade",
+		piles: map[string][]string{
+			"firstPlayerHand": {"blobWheel_1"},
+		},
+		steps: []ruleStep{play("blobWheel_1"), activate("blobWheel_1", Utilization)},
+		locations: map[string]string{
+			"blobWheel_1": "scrapHeap",
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(3, 1, 50)},
+	},
+	{
+		name: "battle pod asks to scrap from the trade row before its abilities",
+		piles: map[string][]string{
+			"firstPlayerHand": {"battlePod_1"},
+		},
+		steps:        []ruleStep{play("battlePod_1")},
+		want:         map[PlayerId]Counters{FirstPlayer: counters(0, 0, 50)},
+		wantRequests: map[PlayerId]UserAction{FirstPlayer: ScrapCardTradeRow},
+	},
+	{
+		name: "battle pod scraps from the trade row and then gives combat",
+		piles: map[string][]string{
+			"firstPlayerHand": {"battlePod_1"},
+			"tradeRow":        {"cutter_1", "cutter_2"},
+			"tradeDeck":       {"corvette_1"},
+		},
+		steps: []ruleStep{play("battlePod_1"), respond(ScrapCardTradeRow, "cutter_1")},
+		locations: map[string]string{
+			"cutter_1":   "scrapHeap",
+			"corvette_1": "tradeRow",
+		},
+		want:         map[PlayerId]Counters{FirstPlayer: counters(0, 4, 50)},
+		wantRequests: map[PlayerId]UserAction{FirstPlayer: NoneAction},
+	},
+	{
+		name: "blob carrier acquires a ship on top of the deck with an ally",
+		piles: map[string][]string{
+			"firstPlayerHand": {"blobCarrier_1", "blobFighter_1"},
+			"firstPlayerDeck": {"scout_1"},
+			"tradeRow":        {"cutter_1"},
+			"tradeDeck":       {"corvette_1"},
+		},
+		steps: []ruleStep{
+			play("blobCarrier_1"),
+			play("blobFighter_1"),
+			activate("blobCarrier_1", BlobCarrierAcquire),
+			respond(AcquireShipForFree, "cutter_1"),
+		},
+		locations: map[string]string{
+			"scout_1":    "firstPlayerHand",
+			"corvette_1": "tradeRow",
+		},
+		tops:      map[string]string{"firstPlayerDeck": "cutter_1"},
+		want:      map[PlayerId]Counters{FirstPlayer: counters(0, 10, 50)},
+		abilities: map[string]ActivatedAbilities{"blobCarrier_1": {BlobCarrierAcquire: false}},
+	},
+	{
+		name: "blob carrier can't acquire without an ally",
+		piles: map[string][]string{
+			"firstPlayerHand": {"blobCarrier_1"},
+		},
+		steps:     []ruleStep{play("blobCarrier_1")},
+		want:      map[PlayerId]Counters{FirstPlayer: counters(0, 7, 50)},
+		abilities: map[string]ActivatedAbilities{"blobCarrier_1": {BlobCarrierAcquire: false}},
+	},
+	{
+		name: "blob destroyer destroys a base and scraps from the trade row",
+		piles: map[string][]string{
+			"firstPlayerHand":   {"blobDestroyer_1", "blobFighter_1"},
+			"secondPlayerBases": {"spaceStation_1"},
+			"tradeRow":          {"cutter_1"},
+			"tradeDeck":         {"corvette_1"},
+		},
+		steps: []ruleStep{
+			play("blobDestroyer_1"),
+			play("blobFighter_1"),
+			activate("blobDestroyer_1", BlobDestroyerDestroyBase),
+			respond(DestroyBaseBlobDestroyer, "spaceStation_1"),
+			respond(ScrapCardTradeRow, "cutter_1"),
+		},
+		locations: map[string]string{
+			"spaceStation_1": "secondPlayerDiscard",
+			"cutter_1":       "scrapHeap",
+			"corvette_1":     "tradeRow",
+		},
+		want:         map[PlayerId]Counters{FirstPlayer: counters(0, 9, 50)},
+		wantRequests: map[PlayerId]UserAction{FirstPlayer: NoneAction},
+	},
+	{
+		name: "blob world draws a card for every blob played this turn",
+		piles: map[string][]string{
+			"firstPlayerBases": {"blobWorld_1"},
+			"firstPlayerHand":  {"blobFighter_1", "blobFighter_2"},
+			"firstPlayerDeck":  {"scout_4", "scout_3", "scout_2", "scout_1"},
+		},
+		steps: []ruleStep{
+			play("blobFighter_1"),
+			play("blobFighter_2"),
+			activate("blobWorld_1", BlobWorldDraw),
+		},
+		locations: map[string]string{
+			"blobWorld_1": "firstPlayerBases",
+		},
+		// Blob World is an ally of both fighters
+		sizes: map[string]int{"firstPlayerHand": 4},
+		want:  map[PlayerId]Counters{FirstPlayer: counters(0, 6, 50)},
+	},
+	{
+		name: "blob world gives combat instead of the draws",
+		piles: map[string][]string{
+			"firstPlayerBases": {"blobWorld_1"},
+		},
+		steps: []ruleStep{activate("blobWorld_1", BlobWorldCombat)},
+		want:  map[PlayerId]Counters{FirstPlayer: counters(0, 5, 50)},
+	},
+}
+
+var starEmpireCases = []ruleCase{
+	{
+		name: "imperial fighters make the opponent discard",
+		piles: map[string][]string{
+			"firstPlayerHand": {"imperialFighter_1", "imperialFighter_2"},
+		},
+		steps: []ruleStep{play("imperialFighter_1"), play("imperialFighter_2")},
+		want: map[PlayerId]Counters{
+			FirstPlayer:  counters(0, 8, 50),
+			SecondPlayer: {Authority: 50, Discard: 2},
+		},
+	},
+	{
+		name: "imperial frigate is scrapped to draw",
+		piles: map[string][]string{
+			"firstPlayerHand": {"imperialFrigate_1", "corvette_1"},
+			"firstPlayerDeck": {"scout_1", "viper_1"},
+		},
+		steps: []ruleStep{play("imperialFrigate_1"), play("corvette_1"), activate("imperialFrigate_1", Utilization)},
+		locations: map[string]string{
+			"imperialFrigate_1": "scrapHeap",
+			"viper_1":           "firstPlayerHand",
+			"scout_1":           "firstPlayerHand",
+		},
+		want: map[PlayerId]Counters{
+			FirstPlayer:  counters(0, 9, 50),
+			SecondPlayer: {Authority: 50, Discard: 1},
+		},
+	},
+	{
+		name: "dreadnaught draws and is scrapped for combat",
+		piles: map[string][]string{
+			"firstPlayerHand": {"dreadnaught_1"},
+			"firstPlayerDeck": {"scout_1"},
+		},
+		steps: []ruleStep{play("dreadnaught_1"), activate("dreadnaught_1", Utilization)},
+		locations: map[string]string{
+			"dreadnaught_1": "scrapHeap",
+			"scout_1":       "firstPlayerHand",
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(0, 12, 50)},
+	},
+	{
+		name: "royal redoubt makes the opponent discard with an ally",
+		piles: map[string][]string{
+			"firstPlayerHand": {"royalRedoubt_1", "imperialFighter_1"},
+		},
+		steps: []ruleStep{play("royalRedoubt_1"), play("imperialFighter_1")},
+		locations: map[string]string{
+			"royalRedoubt_1": "firstPlayerBases",
+		},
+		want: map[PlayerId]Counters{
+			FirstPlayer:  counters(0, 7, 50),
+			SecondPlayer: {Authority: 50, Discard: 2},
+		},
+	},
+	{
+		name: "space station is scrapped for trade",
+		piles: map[string][]string{
+			"firstPlayerHand": {"spaceStation_1", "corvette_1"},
+			"firstPlayerDeck": {"scout_1"},
+		},
+		steps: []ruleStep{play("spaceStation_1"), play("corvette_1"), activate("spaceStation_1", Utilization)},
+		locations: map[string]string{
+			"spaceStation_1": "scrapHeap",
+			"scout_1":        "firstPlayerHand",
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(4, 7, 50)},
+	},
+	{
+		name: "survey ship is scrapped to make the opponent discard",
+		piles: map[string][]string{
+			"firstPlayerHand": {"surveyShip_1"},
+			"firstPlayerDeck": {"scout_1"},
+		},
+		steps: []ruleStep{play("surveyShip_1"), activate("surveyShip_1", Utilization)},
+		locations: map[string]string{
+			"surveyShip_1": "scrapHeap",
+			"scout_1":      "firstPlayerHand",
+		},
+		want: map[PlayerId]Counters{
+			FirstPlayer:  counters(1, 0, 50),
+			SecondPlayer: {Authority: 50, Discard: 1},
+		},
+	},
+	{
+		name: "war world gives combat with an ally",
+		piles: map[string][]string{
+			"firstPlayerHand": {"warWorld_1", "imperialFighter_1"},
+		},
+		steps: []ruleStep{play("warWorld_1"), play("imperialFighter_1")},
+		want: map[PlayerId]Counters{
+			FirstPlayer:  counters(0, 11, 50),
+			SecondPlayer: {Authority: 50, Discard: 1},
+		},
+	},
+	{
+		name: "battlecruiser is scrapped to draw and destroy a base",
+		piles: map[string][]string{
+			"firstPlayerHand":   {"battlecruiser_1", "imperialFighter_1"},
+			"firstPlayerDeck":   {"viper_1", "scout_1"},
+			"secondPlayerBases": {"tradingPost_1"},
+		},
+		steps: []ruleStep{
+			play("battlecruiser_1"),
+			play("imperialFighter_1"),
+			activate("battlecruiser_1", Utilization),
+			respond(DestroyBaseForFree, "tradingPost_1"),
+		},
+		locations: map[string]string{
+			"battlecruiser_1": "scrapHeap",
+			"scout_1":         "firstPlayerHand",
+			"viper_1":         "firstPlayerHand",
+			"tradingPost_1":   "secondPlayerDiscard",
+		},
+		want: map[PlayerId]Counters{
+			FirstPlayer:  counters(0, 9, 50),
+			SecondPlayer: {Authority: 50, Discard: 2},
+		},
+		wantRequests: map[PlayerId]UserAction{FirstPlayer: NoneAction},
+	},
+	{
+		name: "recycling station discards to draw",
+		piles: map[string][]string{
+			"firstPlayerBases": {"recyclingStation_1"},
+			"firstPlayerHand":  {"scout_1", "viper_1"},
+			"firstPlayerDeck":  {"scout_3", "scout_2"},
+		},
+		steps: []ruleStep{
+			activate("recyclingStation_1", RecyclingStation),
+			respond(ActivateRecyclingStation, "scout_1", "viper_1"),
+		},
+		locations: map[string]string{
+			"scout_1": "firstPlayerDiscard",
+			"viper_1": "firstPlayerDiscard",
+			"scout_2": "firstPlayerHand",
+			"scout_3": "firstPlayerHand",
+		},
+		sizes:        map[string]int{"firstPlayerHand": 2},
+		wantRequests: map[PlayerId]UserAction{FirstPlayer: NoneAction},
+	},
+	{
+		name: "fleet hq gives combat for every ship played after it",
+		piles: map[string][]string{
+			"firstPlayerHand": {"fleetHQ_1", "scout_1", "viper_1"},
+		},
+		steps: []ruleStep{play("fleetHQ_1"), play("scout_1"), play("viper_1")},
+		want:  map[PlayerId]Counters{FirstPlayer: counters(1, 3, 50)},
+	},
+}
+
+var machineCultCases = []ruleCase{
+	{
+		name: "battle mech scraps a card before its abilities",
+		piles: map[string][]string{
+			"firstPlayerHand": {"battleMech_1", "viper_1"},
+		},
+		steps: []ruleStep{play("battleMech_1"), respond(ScrapCard, "viper_1")},
+		locations: map[string]string{
+			"viper_1": "scrapHeap",
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(0, 4, 50)},
+	},
+	{
+		name: "deferred abilities of the machine cult make an ally chain",
+		piles: map[string][]string{
+			"firstPlayerHand": {"missileBot_1", "tradeBot_1"},
+		},
+		steps: []ruleStep{
+			play("missileBot_1"),
+			respond(ScrapCard),
+			play("tradeBot_1"),
+			respond(ScrapCard),
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(1, 6, 50)},
+	},
+	{
+		name: "battle mech draws after a supply bot",
+		piles: map[string][]string{
+			"firstPlayerHand": {"supplyBot_1", "battleMech_1"},
+			"firstPlayerDeck": {"scout_1"},
+		},
+		steps: []ruleStep{
+			play("supplyBot_1"),
+			respond(ScrapCard),
+			play("battleMech_1"),
+			respond(ScrapCard),
+		},
+		locations: map[string]string{
+			"scout_1": "firstPlayerHand",
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(2, 6, 50)},
+	},
+	{
+		name: "missile mech destroys a base before its abilities",
+		piles: map[string][]string{
+			"firstPlayerHand":   {"missileMech_1"},
+			"secondPlayerBases": {"spaceStation_1"},
+		},
+		steps: []ruleStep{play("missileMech_1"), respond(DestroyBaseForFree, "spaceStation_1")},
+		locations: map[string]string{
+			"spaceStation_1": "secondPlayerDiscard",
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(0, 6, 50)},
+	},
+	{
+		name: "patrol mech chooses one ability and scraps with an ally",
+		piles: map[string][]string{
+			"firstPlayerHand":    {"patrolMech_1", "patrolMech_2"},
+			"firstPlayerDiscard": {"viper_1"},
+		},
+		steps: []ruleStep{
+			play("patrolMech_1"),
+			activate("patrolMech_1", PatrolMechCombat),
+			play("patrolMech_2"),
+			activate("patrolMech_2", PatrolMechTrade),
+			activate("patrolMech_1", PatrolMechScrap),
+			respond(ScrapCard, "viper_1"),
+		},
+		locations: map[string]string{
+			"viper_1": "scrapHeap",
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(3, 5, 50)},
+		abilities: map[string]ActivatedAbilities{
+			"patrolMech_1": {PatrolMechTrade: false, PatrolMechCombat: false, PatrolMechScrap: false},
+			"patrolMech_2": {PatrolMechTrade: false, PatrolMechCombat: false, PatrolMechScrap: true},
+		},
+	},
+	{
+		name: "junkyard scraps a card",
+		piles: map[string][]string{
+			"firstPlayerBases":   {"junkyard_1"},
+			"firstPlayerDiscard": {"scout_1"},
+		},
+		steps: []ruleStep{activate("junkyard_1", Junkyard), respond(ScrapCard, "scout_1")},
+		locations: map[string]string{
+			"scout_1": "scrapHeap",
+		},
+	},
+	{
+		name: "machine base draws and scraps a card of the hand",
+		piles: map[string][]string{
+			"firstPlayerBases": {"machineBase_1"},
+			"firstPlayerHand":  {"viper_1"},
+			"firstPlayerDeck":  {"scout_1"},
+		},
+		steps: []ruleStep{activate("machineBase_1", MachineBase), respond(ScrapCardInHand, "viper_1")},
+		locations: map[string]string{
+			"viper_1": "scrapHeap",
+			"scout_1": "firstPlayerHand",
+		},
+		wantRequests: map[PlayerId]UserAction{FirstPlayer: NoneAction},
+	},
+	{
+		name: "brain world scraps cards to draw",
+		piles: map[string][]string{
+			"firstPlayerBases":   {"brainWorld_1"},
+			"firstPlayerHand":    {"viper_1"},
+			"firstPlayerDiscard": {"scout_1"},
+			"firstPlayerDeck":    {"scout_3", "scout_2"},
+		},
+		steps: []ruleStep{activate("brainWorld_1", BrainWorld), respond(ActivateBrainWorld, "viper_1", "scout_1")},
+		locations: map[string]string{
+			"viper_1": "scrapHeap",
+			"scout_1": "scrapHeap",
+			"scout_2": "firstPlayerHand",
+			"scout_3": "firstPlayerHand",
+		},
+	},
+	{
+		name: "mech world is an ally of every faction",
+		piles: map[string][]string{
+			"firstPlayerHand": {"mechWorld_1", "blobFighter_1"},
+			"firstPlayerDeck": {"scout_1"},
+		},
+		steps: []ruleStep{play("mechWorld_1"), respond(ActivateMechWorld), play("blobFighter_1")},
+		locations: map[string]string{
+			"scout_1": "firstPlayerHand",
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(0, 3, 50)},
+	},
+	{
+		name: "mech world in play is an ally of the ships of the scenario",
+		piles: map[string][]string{
+			"firstPlayerBases": {"mechWorld_1"},
+			"firstPlayerHand":  {"cutter_1"},
+		},
+		steps: []ruleStep{play("cutter_1")},
+		want:  map[PlayerId]Counters{FirstPlayer: counters(2, 4, 54)},
+	},
+	{
+		name: "stealth needle copies a ship and is scrapped in its place",
+		piles: map[string][]string{
+			"firstPlayerTable": {"ram_1"},
+			"firstPlayerHand":  {"stealthNeedle_1"},
+		},
+		counters: map[PlayerId]Counters{FirstPlayer: counters(0, 5, 50)},
+		steps: []ruleStep{
+			play("stealthNeedle_1"),
+			respond(ActivateNeedle, "ram_1"),
+			activate("ram_1"+NEEDLE_SUFFIX, Utilization),
+		},
+		locations: map[string]string{
+			"stealthNeedle_1": "scrapHeap",
+			"ram_1":           "firstPlayerTable",
+		},
+		// The copy is the ally of the ram
+		want:      map[PlayerId]Counters{FirstPlayer: counters(3, 14, 50)},
+		abilities: map[string]ActivatedAbilities{"ram_1" + NEEDLE_SUFFIX: {Utilization: false}},
+	},
+	{
+		name: "stealth needle copies the ally ability of blob carrier",
+		piles: map[string][]string{
+			"firstPlayerTable": {"blobCarrier_1", "blobFighter_1"},
+			"firstPlayerHand":  {"stealthNeedle_1"},
+			"tradeRow":         {"cutter_1"},
+		},
+		counters: map[PlayerId]Counters{FirstPlayer: counters(0, 10, 50)},
+		steps: []ruleStep{
+			play("stealthNeedle_1"),
+			respond(ActivateNeedle, "blobCarrier_1"),
+			activate("blobCarrier_1"+NEEDLE_SUFFIX, BlobCarrierAcquire),
+			respond(AcquireShipForFree, "cutter_1"),
+		},
+		locations: map[string]string{
+			"stealthNeedle_1": "firstPlayerTable",
+		},
+		tops: map[string]string{"firstPlayerDeck": "cutter_1"},
+		want: map[PlayerId]Counters{FirstPlayer: counters(0, 17, 50)},
+	},
+	{
+		name: "stealth needle copies the scrap of battle mech before its abilities",
+		piles: map[string][]string{
+			"firstPlayerTable":   {"battleMech_1"},
+			"firstPlayerHand":    {"stealthNeedle_1"},
+			"firstPlayerDiscard": {"viper_1"},
+			"firstPlayerDeck":    {"scout_2", "scout_1"},
+		},
+		counters: map[PlayerId]Counters{FirstPlayer: counters(0, 4, 50)},
+		steps: []ruleStep{
+			play("stealthNeedle_1"),
+			respond(ActivateNeedle, "battleMech_1"),
+			respond(ScrapCard, "viper_1"),
+		},
+		// The needle is the ally of the battle mech and the copy is the
+		// ally of both
+		locations: map[string]string{
+			"viper_1": "scrapHeap",
+			"scout_1": "firstPlayerHand",
+			"scout_2": "firstPlayerHand",
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(0, 8, 50)},
+	},
+	{
+		name: "battle station is scrapped for combat",
+		piles: map[string][]string{
+			"firstPlayerBases": {"battleStation_1"},
+		},
+		steps: []ruleStep{activate("battleStation_1", Utilization)},
+		locations: map[string]string{
+			"battleStation_1": "scrapHeap",
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(0, 5, 50)},
+	},
+}
+
+var tradeFederationCases = []ruleCase{
+	{
+		name: "federation shuttles give authority as allies",
+		piles: map[string][]string{
+			"firstPlayerHand": {"federationShuttle_1", "federationShuttle_2"},
+		},
+		steps: []ruleStep{play("federationShuttle_1"), play("federationShuttle_2")},
+		want:  map[PlayerId]Counters{FirstPlayer: counters(4, 0, 58)},
+	},
+	{
+		name: "cutters give combat as allies",
+		piles: map[string][]string{
+			"firstPlayerHand": {"cutter_1", "cutter_2"},
+		},
+		steps: []ruleStep{play("cutter_1"), play("cutter_2")},
+		want:  map[PlayerId]Counters{FirstPlayer: counters(4, 8, 58)},
+	},
+	{
+		name: "trade escort draws with an ally",
+		piles: map[string][]string{
+			"firstPlayerHand": {"tradeEscort_1", "cutter_1"},
+			"firstPlayerDeck": {"scout_1"},
+		},
+		steps: []ruleStep{play("tradeEscort_1"), play("cutter_1")},
+		locations: map[string]string{
+			"scout_1": "firstPlayerHand",
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(2, 8, 58)},
+	},
+	{
+		name: "flagship draws and gives authority with an ally",
+		piles: map[string][]string{
+			"firstPlayerHand": {"flagship_1", "cutter_1"},
+			"firstPlayerDeck": {"scout_1"},
+		},
+		steps: []ruleStep{play("flagship_1"), play("cutter_1")},
+		locations: map[string]string{
+			"scout_1": "firstPlayerHand",
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(2, 9, 59)},
+	},
+	{
+		name: "command ship draws two and destroys a base with an ally",
+		piles: map[string][]string{
+			"firstPlayerHand":   {"commandShip_1", "federationShuttle_1"},
+			"firstPlayerDeck":   {"scout_2", "scout_1"},
+			"secondPlayerBases": {"barterWorld_1"},
+		},
+		steps: []ruleStep{
+			play("commandShip_1"),
+			play("federationShuttle_1"),
+			activate("commandShip_1", CommandShipDestroyBase),
+			respond(DestroyBaseForFree, "barterWorld_1"),
+		},
+		locations: map[string]string{
+			"scout_1":       "firstPlayerHand",
+			"scout_2":       "firstPlayerHand",
+			"barterWorld_1": "secondPlayerDiscard",
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(2, 5, 58)},
+	},
+	{
+		name: "trading post gives authority and is scrapped for combat",
+		piles: map[string][]string{
+			"firstPlayerHand": {"tradingPost_1"},
+		},
+		steps: []ruleStep{
+			play("tradingPost_1"),
+			activate("tradingPost_1", TradingPostAuthority),
+			activate("tradingPost_1", Utilization),
+		},
+		locations: map[string]string{
+			"tradingPost_1": "scrapHeap",
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(0, 3, 51)},
+		abilities: map[string]ActivatedAbilities{
+			"tradingPost_1": {TradingPostAuthority: false, TradingPostTrade: false},
+		},
+	},
+	{
+		name: "barter world gives trade and is scrapped for combat",
+		piles: map[string][]string{
+			"firstPlayerHand": {"barterWorld_1"},
+		},
+		steps: []ruleStep{
+			play("barterWorld_1"),
+			activate("barterWorld_1", BarterWorldTrade),
+			activate("barterWorld_1", Utilization),
+		},
+		locations: map[string]string{
+			"barterWorld_1": "scrapHeap",
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(2, 5, 50)},
+	},
+	{
+		name: "defense center gives combat with an ally",
+		piles: map[string][]string{
+			"firstPlayerHand": {"defenseCenter_1", "cutter_1"},
+		},
+		steps: []ruleStep{
+			play("defenseCenter_1"),
+			play("cutter_1"),
+			activate("defenseCenter_1", DefenseCenterCombat),
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(2, 8, 54)},
+		abilities: map[string]ActivatedAbilities{
+			"defenseCenter_1": {DefenseCenterAuthority: false, DefenseCenterCombat: false},
+		},
+	},
+	{
+		name: "port of call is scrapped to draw and destroy a base",
+		piles: map[string][]string{
+			"firstPlayerHand":   {"portOfCall_1"},
+			"firstPlayerDeck":   {"scout_1"},
+			"secondPlayerBases": {"spaceStation_1"},
+		},
+		steps: []ruleStep{
+			play("portOfCall_1"),
+			activate("portOfCall_1", Utilization),
+			respond(DestroyBaseForFree, "spaceStation_1"),
+		},
+		locations: map[string]string{
+			"portOfCall_1":   "scrapHeap",
+			"scout_1":        "firstPlayerHand",
+			"spaceStation_1": "secondPlayerDiscard",
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(3, 0, 50)},
+	},
+	{
+		name: "freighter puts the next ship bought on top of the deck",
+		piles: map[string][]string{
+			"firstPlayerHand": {"freighter_1", "cutter_1"},
+			"tradeRow":        {"corvette_1"},
+			"tradeDeck":       {"ram_1"},
+		},
+		steps: []ruleStep{
+			play("freighter_1"),
+			play("cutter_1"),
+			{FirstPlayer, "4,corvette_1"},
+		},
+		locations: map[string]string{
+			"ram_1": "tradeRow",
+		},
+		tops: map[string]string{"firstPlayerDeck": "corvette_1"},
+		want: map[PlayerId]Counters{FirstPlayer: counters(4, 4, 54)},
+	},
+	{
+		name: "central office draws with an ally and puts a ship on top",
+		piles: map[string][]string{
+			"firstPlayerHand": {"centralOffice_1", "cutter_1"},
+			"firstPlayerDeck": {"scout_1"},
+			"tradeRow":        {"corvette_1"},
+		},
+		steps: []ruleStep{
+			play("centralOffice_1"),
+			play("cutter_1"),
+			{FirstPlayer, "4,corvette_1"},
+		},
+		locations: map[string]string{
+			"scout_1":         "firstPlayerHand",
+			"centralOffice_1": "firstPlayerBases",
+		},
+		tops: map[string]string{"firstPlayerDeck": "corvette_1"},
+		want: map[PlayerId]Counters{FirstPlayer: counters(2, 4, 54)},
+	},
+	{
+		name: "embassy yacht draws two with two bases",
+		piles: map[string][]string{
+			"firstPlayerBases": {"tradingPost_1", "barterWorld_1"},
+			"firstPlayerHand":  {"embassyYacht_1"},
+			"firstPlayerDeck":  {"scout_2", "scout_1"},
+		},
+		steps: []ruleStep{play("embassyYacht_1")},
+		locations: map[string]string{
+			"scout_1": "firstPlayerHand",
+			"scout_2": "firstPlayerHand",
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(2, 0, 53)},
+	},
+	{
+		name: "embassy yacht doesn't draw with one base",
+		piles: map[string][]string{
+			"firstPlayerBases": {"tradingPost_1"},
+			"firstPlayerHand":  {"embassyYacht_1"},
+		},
+		steps: []ruleStep{play("embassyYacht_1")},
+		sizes: map[string]int{"firstPlayerHand": 0},
+		want:  map[PlayerId]Counters{FirstPlayer: counters(2, 0, 53)},
+	},
+}
+
+var turnCases = []ruleCase{
+	{
+		name:  "undo takes the played card back",
+		piles: map[string][]string{"firstPlayerHand": {"viper_1"}},
+		steps: []ruleStep{play("viper_1"), {FirstPlayer, "20"}},
+		locations: map[string]string{
+			"viper_1": "firstPlayerHand",
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(0, 0, 50)},
+	},
+	{
+		name: "buying puts the card in the discard pile and refills the trade row",
+		piles: map[string][]string{
+			"tradeRow":  {"cutter_1"},
+			"tradeDeck": {"ram_1"},
+		},
+		counters: map[PlayerId]Counters{FirstPlayer: counters(3, 0, 50)},
+		steps:    []ruleStep{{FirstPlayer, "4,cutter_1"}},
+		locations: map[string]string{
+			"cutter_1": "firstPlayerDiscard",
+			"ram_1":    "tradeRow",
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(1, 0, 50)},
+	},
+	{
+		name:     "buying an explorer doesn't refill the trade row",
+		counters: map[PlayerId]Counters{FirstPlayer: counters(2, 0, 50)},
+		steps:    []ruleStep{{FirstPlayer, "4,explorer_1"}},
+		locations: map[string]string{
+			"explorer_1": "firstPlayerDiscard",
+		},
+		sizes: map[string]int{"tradeRow": 0},
+		want:  map[PlayerId]Counters{FirstPlayer: counters(0, 0, 50)},
+	},
+	{
+		name:     "combat damages the opponent",
+		counters: map[PlayerId]Counters{FirstPlayer: counters(0, 5, 50)},
+		steps:    []ruleStep{{FirstPlayer, "3,5"}},
+		want: map[PlayerId]Counters{
+			FirstPlayer:  counters(0, 0, 50),
+			SecondPlayer: counters(0, 0, 45),
+		},
+	},
+	{
+		name:     "combat destroys a base",
+		piles:    map[string][]string{"secondPlayerBases": {"spaceStation_1"}},
+		counters: map[PlayerId]Counters{FirstPlayer: counters(0, 5, 50)},
+		steps:    []ruleStep{{FirstPlayer, "7,spaceStation_1"}},
+		locations: map[string]string{
+			"spaceStation_1": "secondPlayerDiscard",
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(0, 1, 50)},
+	},
+	{
+		name:     "the turn ends with the ships discarded and a new hand",
+		piles:    map[string][]string{"firstPlayerTable": {"viper_1"}},
+		counters: map[PlayerId]Counters{FirstPlayer: counters(2, 3, 50)},
+		steps:    []ruleStep{{FirstPlayer, "2"}},
+		locations: map[string]string{
+			"viper_1": "firstPlayerDiscard",
+		},
+		sizes:        map[string]int{"firstPlayerHand": 5, "firstPlayerDeck": 4},
+		want:         map[PlayerId]Counters{FirstPlayer: counters(0, 0, 50)},
+		turn:         SecondPlayer,
+		wantRequests: map[PlayerId]UserAction{SecondPlayer: Start},
+	},
+	{
+		name: "the opponent discards at the start of the turn",
+		piles: map[string][]string{
+			"firstPlayerHand":  {"imperialFighter_1"},
+			"secondPlayerHand": {"scout_9"},
+		},
+		steps: []ruleStep{
+			play("imperialFighter_1"),
+			{FirstPlayer, "2"},
+			by(SecondPlayer, respond(DiscardCard, "scout_9")),
+		},
+		locations: map[string]string{
+			"scout_9": "secondPlayerDiscard",
+		},
+		sizes:        map[string]int{"secondPlayerHand": 0},
+		want:         map[PlayerId]Counters{SecondPlayer: counters(0, 0, 50)},
+		turn:         SecondPlayer,
+		wantRequests: map[PlayerId]UserAction{SecondPlayer: Start},
+	},
+	{
+		name: "the discard pile is shuffled into the empty deck",
+		piles: map[string][]string{
+			"firstPlayerHand": {"corvette_1"},
+			"firstPlayerDiscard": {
+				"scout_1", "scout_2", "scout_3", "scout_4", "scout_5",
+				"scout_6", "scout_7", "scout_8", "viper_1", "viper_2",
+			},
+		},
+		steps: []ruleStep{play("corvette_1")},
+		sizes: map[string]int{
+			"firstPlayerHand":    1,
+			"firstPlayerDeck":    9,
+			"firstPlayerDiscard": 0,
+		},
+	},
+}
+
+// The moves the rules don't allow change nothing
+var refusedCases = []ruleCase{
+	{
+		name:     "a card costing more than the trade isn't bought",
+		piles:    map[string][]string{"tradeRow": {"cutter_1"}},
+		counters: map[PlayerId]Counters{FirstPlayer: counters(1, 0, 50)},
+		steps:    []ruleStep{{FirstPlayer, "4,cutter_1"}},
+		locations: map[string]string{
+			"cutter_1": "tradeRow",
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(1, 0, 50)},
+	},
+	{
+		name:     "a card out of the trade row isn't bought",
+		piles:    map[string][]string{"secondPlayerDiscard": {"cutter_1"}},
+		counters: map[PlayerId]Counters{FirstPlayer: counters(5, 0, 50)},
+		steps:    []ruleStep{{FirstPlayer, "4,cutter_1"}},
+		locations: map[string]string{
+			"cutter_1": "secondPlayerDiscard",
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(5, 0, 50)},
+	},
+	{
+		name:     "a ship out of the trade row isn't acquired for free",
+		piles:    map[string][]string{"secondPlayerDiscard": {"cutter_1"}},
+		requests: map[PlayerId]ActionRequest{FirstPlayer: {Action: AcquireShipForFree}},
+		steps:    []ruleStep{respond(AcquireShipForFree, "cutter_1")},
+		locations: map[string]string{
+			"cutter_1": "secondPlayerDiscard",
+		},
+	},
+	{
+		name:     "the damage doesn't exceed the combat",
+		counters: map[PlayerId]Counters{FirstPlayer: counters(0, 3, 50)},
+		steps:    []ruleStep{{FirstPlayer, "3,5"}},
+		want: map[PlayerId]Counters{
+			FirstPlayer:  counters(0, 3, 50),
+			SecondPlayer: counters(0, 0, 50),
+		},
+	},
+	{
+		name:     "a negative damage doesn't heal",
+		counters: map[PlayerId]Counters{FirstPlayer: counters(0, 3, 50)},
+		steps:    []ruleStep{{FirstPlayer, "3,-5"}},
+		want: map[PlayerId]Counters{
+			FirstPlayer:  counters(0, 3, 50),
+			SecondPlayer: counters(0, 0, 50),
+		},
+	},
+	{
+		name:     "a base with more defense than the combat isn't destroyed",
+		piles:    map[string][]string{"secondPlayerBases": {"spaceStation_1"}},
+		counters: map[PlayerId]Counters{FirstPlayer: counters(0, 3, 50)},
+		steps:    []ruleStep{{FirstPlayer, "7,spaceStation_1"}},
+		locations: map[string]string{
+			"spaceStation_1": "secondPlayerBases",
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(0, 3, 50)},
+	},
+	{
+		name:  "a card out of the hand isn't played",
+		piles: map[string][]string{"firstPlayerDiscard": {"viper_1"}},
+		steps: []ruleStep{play("viper_1")},
+		locations: map[string]string{
+			"viper_1": "firstPlayerDiscard",
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(0, 0, 50)},
+	},
+	{
+		name:  "a card out of the hand isn't discarded",
+		piles: map[string][]string{"secondPlayerDeck": {"scout_9"}},
+		counters: map[PlayerId]Counters{
+			SecondPlayer: {Authority: 50, Discard: 1},
+		},
+		startTurn: SecondPlayer,
+		steps:     []ruleStep{by(SecondPlayer, respond(DiscardCard, "scout_9"))},
+		locations: map[string]string{
+			"scout_9": "secondPlayerDeck",
+		},
+		want: map[PlayerId]Counters{SecondPlayer: {Authority: 50, Discard: 1}},
+	},
+	{
+		name:  "nothing is discarded unless asked",
+		piles: map[string][]string{"firstPlayerHand": {"scout_1"}},
+		steps: []ruleStep{respond(DiscardCard, "scout_1")},
+		locations: map[string]string{
+			"scout_1": "firstPlayerHand",
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(0, 0, 50)},
+	},
+	{
+		name:     "a card out of the hand and the discard pile isn't scrapped",
+		piles:    map[string][]string{"tradeRow": {"cutter_1"}},
+		requests: map[PlayerId]ActionRequest{FirstPlayer: {Action: ScrapCard}},
+		steps:    []ruleStep{respond(ScrapCard, "cutter_1")},
+		locations: map[string]string{
+			"cutter_1": "tradeRow",
+		},
+	},
+	{
+		name:     "a card out of the trade row isn't scrapped from it",
+		piles:    map[string][]string{"firstPlayerHand": {"scout_1"}},
+		requests: map[PlayerId]ActionRequest{FirstPlayer: {Action: ScrapCardTradeRow}},
+		steps:    []ruleStep{respond(ScrapCardTradeRow, "scout_1")},
+		locations: map[string]string{
+			"scout_1": "firstPlayerHand",
+		},
+	},
+	{
+		name:  "a card isn't scrapped unless asked",
+		piles: map[string][]string{"firstPlayerHand": {"scout_1"}},
+		steps: []ruleStep{respond(ScrapCard, "scout_1")},
+		locations: map[string]string{
+			"scout_1": "firstPlayerHand",
+		},
+	},
+	{
+		name:  "nothing is undone once the opponent turned undo off",
+		piles: map[string][]string{"firstPlayerHand": {"viper_1"}},
+		steps: []ruleStep{play("viper_1"), {SecondPlayer, "21"}, {FirstPlayer, "20"}},
+		locations: map[string]string{
+			"viper_1": "firstPlayerTable",
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(0, 1, 50)},
+	},
+	{
+		name:  "the other player doesn't play in the turn",
+		piles: map[string][]string{"secondPlayerHand": {"viper_3"}},
+		steps: []ruleStep{by(SecondPlayer, play("viper_3"))},
+		locations: map[string]string{
+			"viper_3": "secondPlayerHand",
+		},
+		want: map[PlayerId]Counters{SecondPlayer: counters(0, 0, 50)},
+	},
+	{
+		name:  "the other player doesn't end the turn",
+		piles: map[string][]string{"firstPlayerTable": {"viper_1"}},
+		steps: []ruleStep{{SecondPlayer, "2"}},
+		locations: map[string]string{
+			"viper_1": "firstPlayerTable",
+		},
+		turn: FirstPlayer,
+	},
+	{
+		name:  "the other player doesn't start the turn",
+		piles: map[string][]string{"secondPlayerBases": {"theHive_1"}},
+		steps: []ruleStep{{SecondPlayer, "6"}},
+		want:  map[PlayerId]Counters{SecondPlayer: counters(0, 0, 50)},
+		turn:  FirstPlayer,
+	},
+	{
+		name:      "the base of the opponent isn't activated",
+		piles:     map[string][]string{"secondPlayerBases": {"battleStation_1"}},
+		activated: map[string]ActivatedAbilities{"battleStation_1": {Utilization: true}},
+		steps:     []ruleStep{activate("battleStation_1", Utilization)},
+		locations: map[string]string{
+			"battleStation_1": "secondPlayerBases",
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(0, 0, 50)},
+	},
+	{
+		name:      "a used ability isn't activated again",
+		piles:     map[string][]string{"firstPlayerBases": {"battleStation_1"}},
+		activated: map[string]ActivatedAbilities{"battleStation_1": {Utilization: false}},
+		steps:     []ruleStep{activate("battleStation_1", Utilization)},
+		locations: map[string]string{
+			"battleStation_1": "firstPlayerBases",
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(0, 0, 50)},
+	},
+	{
+		name:  "an ability of a card in the hand isn't activated",
+		piles: map[string][]string{"firstPlayerHand": {"explorer_1"}},
+		steps: []ruleStep{activate("explorer_1", Utilization)},
+		locations: map[string]string{
+			"explorer_1": "firstPlayerHand",
+		},
+		want: map[PlayerId]Counters{FirstPlayer: counters(0, 0, 50)},
+	},
+}
+
+func runRuleCases(t *testing.T, cases []ruleCase) {
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			runRuleCase(t, c)
+		})
+	}
+}
+
+func TestUnalignedCards(t *testing.T) {
+	runRuleCases(t, unalignedCases)
+}
+
+func TestBlobCards(t *testing.T) {
+	runRuleCases(t, blobCases)
+}
+
+func TestStarEmpireCards(t *testing.T) {
+	runRuleCases(t, starEmpireCases)
+}
+
+func TestMachineCultCards(t *testing.T) {
+	runRuleCases(t, machineCultCases)
+}
+
+func TestTradeFederationCards(t *testing.T) {
+	runRuleCases(t, tradeFederationCases)
+}
+
+func TestTurn(t *testing.T) {
+	runRuleCases(t, turnCases)
+}
+
+func TestRefusedMoves(t *testing.T) {
+	runRuleCases(t, refusedCases)
+}
+
+// Every card of the deck has to be played or used in some case
+func TestRulesCoverEveryCard(t *testing.T) {
+	used := make(map[string]bool)
+	groups := [][]ruleCase{unalignedCases, blobCases, starEmpireCases, machineCultCases, tradeFederationCases, turnCases}
+	for _, cases := range groups {
+		for _, c := range cases {
+			for _, step := range c.steps {
+				for _, arg := range strings.Split(step.message, ",")[1:] {
+					used[strings.Split(arg, "_")[0]] = true
+				}
+			}
+		}
+	}
+	for key := range *getDeck() {
+		if !used[key] {
+			t.Errorf("no case for %s", key)
+		}
+	}
+}
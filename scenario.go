@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Scenario is a position the game starts from instead of the deal, e.g. to
+// reproduce a rules bug or to set up a puzzle. The cards which aren't
+// listed stay under the listed ones in the piles they are put in before the
+// deal, nothing is shuffled.
+type Scenario struct {
+	Options GameOptions `json:"options"`
+	// Card ids by the location names, from the bottom to the top
+	Piles    map[string][]string   `json:"piles"`
+	Counters map[PlayerId]Counters `json:"counters"`
+	// The first player of the setup by default
+	Turn               PlayerId                      `json:"turn"`
+	ActionRequests     map[PlayerId]ActionRequest    `json:"actionRequests"`
+	ActivatedAbilities map[string]ActivatedAbilities `json:"activatedAbilities"`
+}
+
+type WrongScenarioError struct {
+	reason string
+}
+
+func (e *WrongScenarioError) Error() string {
+	return fmt.Sprintf("wrong scenario: %s", e.reason)
+}
+
+var locationNames = map[CardLocation]string{
+	TradeDeck:           "tradeDeck",
+	TradeRow:            "tradeRow",
+	Explorers:           "explorers",
+	ScrapHeap:           "scrapHeap",
+	GambitDeck:          "gambitDeck",
+	FirstPlayerDeck:     "firstPlayerDeck",
+	FirstPlayerHand:     "firstPlayerHand",
+	FirstPlayerTable:    "firstPlayerTable",
+	FirstPlayerDiscard:  "firstPlayerDiscard",
+	FirstPlayerBases:    "firstPlayerBases",
+	FirstPlayerGambits:  "firstPlayerGambits",
+	SecondPlayerDeck:    "secondPlayerDeck",
+	SecondPlayerHand:    "secondPlayerHand",
+	SecondPlayerTable:   "secondPlayerTable",
+	SecondPlayerDiscard: "secondPlayerDiscard",
+	SecondPlayerBases:   "secondPlayerBases",
+	SecondPlayerGambits: "secondPlayerGambits",
+	ThirdPlayerDeck:     "thirdPlayerDeck",
+	ThirdPlayerHand:     "thirdPlayerHand",
+	ThirdPlayerTable:    "thirdPlayerTable",
+	ThirdPlayerDiscard:  "thirdPlayerDiscard",
+	ThirdPlayerBases:    "thirdPlayerBases",
+	ThirdPlayerGambits:  "thirdPlayerGambits",
+	FourthPlayerDeck:    "fourthPlayerDeck",
+	FourthPlayerHand:    "fourthPlayerHand",
+	FourthPlayerTable:   "fourthPlayerTable",
+	FourthPlayerDiscard: "fourthPlayerDiscard",
+	FourthPlayerBases:   "fourthPlayerBases",
+	FourthPlayerGambits: "fourthPlayerGambits",
+}
+
+func locationByName(name string) (CardLocation, bool) {
+	for location, locationName := range locationNames {
+		if locationName == name {
+			return location, true
+		}
+	}
+	return UndefinedLocation, false
+}
+
+// resolve validates the options of the scenario and fills the setup up
+func (sc *Scenario) resolve() error {
+	if _, ok := getChallenges()[sc.Options.Challenge]; sc.Options.Challenge != "" && !ok {
+		return &WrongScenarioError{"unknown challenge"}
+	}
+	err := validateSeating(sc.Options.Players, sc.Options.Format)
+	if err != nil {
+		return err
+	}
+	err = resolveSetup(&sc.Options)
+	if err != nil {
+		return err
+	}
+	// The position isn't reached by playing
+	sc.Options.Unrated = true
+	if sc.Turn == 0 {
+		sc.Turn = sc.Options.Setup.FirstPlayer
+	}
+	return nil
+}
+
+// load puts the position into the new state and the middleware, the
+// scenario has to be resolved
+func (sc *Scenario) load(state *State, middleware *Middleware) error {
+	if !isSeated(state, sc.Turn) {
+		return &WrongScenarioError{fmt.Sprintf("no seat %d", sc.Turn)}
+	}
+	for name := range sc.Piles {
+		if _, ok := locationByName(name); !ok {
+			return &WrongScenarioError{fmt.Sprintf("unknown location %q", name)}
+		}
+	}
+	placed := make(map[string]bool)
+	for _, location := range sortedLocations(sc.Piles) {
+		for _, id := range sc.Piles[locationNames[location]] {
+			if placed[id] {
+				return &WrongScenarioError{fmt.Sprintf("card %s is listed twice", id)}
+			}
+			placed[id] = true
+			if !state.placeCard(id, location) {
+				return &WrongScenarioError{fmt.Sprintf("unknown card %s", id)}
+			}
+		}
+	}
+	for player, counters := range sc.Counters {
+		if !isSeated(state, player) {
+			return &WrongScenarioError{fmt.Sprintf("no seat %d", player)}
+		}
+		*state.counters(player) = counters
+	}
+	for player, request := range sc.ActionRequests {
+		if !isSeated(state, player) {
+			return &WrongScenarioError{fmt.Sprintf("no seat %d", player)}
+		}
+		if _, ok := state.Cards[request.CardId]; request.CardId != "" && !ok {
+			return &WrongScenarioError{fmt.Sprintf("unknown card %s", request.CardId)}
+		}
+		*state.actionRequest(player) = request
+	}
+	// The cards in play of the turn were played or started, their own
+	// activated abilities are available unless listed otherwise
+	for _, player := range turnPlayers(state, sc.Turn) {
+		locations := seatLocations[player]
+		inPlay := append(state.pile(locations.Table), state.pile(locations.Bases)...)
+		for _, id := range append(inPlay, state.pile(locations.Gambits)...) {
+			card, ok := (*middleware.deck)[strings.Split(id, "_")[0]]
+			if !ok {
+				continue
+			}
+			for _, ability := range card.abilities {
+				if ability.group == Primary && ability.actionType == Activated {
+					if state.ActivatedAbilities[id] == nil {
+						state.ActivatedAbilities[id] = make(ActivatedAbilities)
+					}
+					state.ActivatedAbilities[id][ability.id] = true
+				}
+			}
+		}
+	}
+	for id, abilities := range sc.ActivatedAbilities {
+		if _, ok := state.Cards[strings.TrimSuffix(id, NEEDLE_SUFFIX)]; !ok {
+			return &WrongScenarioError{fmt.Sprintf("unknown card %s", id)}
+		}
+		state.ActivatedAbilities[id] = make(ActivatedAbilities)
+		for abilityId, value := range abilities {
+			state.ActivatedAbilities[id][abilityId] = value
+		}
+	}
+	state.Turn = sc.Turn
+	middleware.rebuildAllyState(sc.Turn, state)
+	return nil
+}
+
+// sortedLocations lists the locations of the piles in the order of the
+// constants so the cards are placed the same way every time
+func sortedLocations(piles map[string][]string) []CardLocation {
+	locations := []CardLocation{}
+	for location := TradeDeck; location <= FourthPlayerGambits; location++ {
+		if _, ok := piles[locationNames[location]]; ok {
+			locations = append(locations, location)
+		}
+	}
+	return locations
+}
+
+// rebuildAllyState sets the ally state up as if the cards the player has in
+// play were played in the order of the piles, the ally abilities of a
+// faction stay pending until the second card of the faction is played
+func (m *Middleware) rebuildAllyState(player PlayerId, state *State) {
+	m.resetAllyState()
+	locations := seatLocations[player]
+	for _, id := range append(state.pile(locations.Bases), state.pile(locations.Table)...) {
+		key := strings.Split(id, "_")[0]
+		card, ok := (*m.deck)[key]
+		if !ok {
+			continue
+		}
+		if _, ok := m.allyState.flags[card.faction]; ok {
+			if m.allyState.flags[card.faction] {
+				m.allyState.abilities[card.faction] = []*CardAbility{}
+			} else {
+				m.allyState.flags[card.faction] = true
+				for _, ability := range card.abilities {
+					if ability.group == Ally {
+						m.allyState.abilities[card.faction] = append(m.allyState.abilities[card.faction], &CardAbility{ability: ability, cardId: id})
+					}
+				}
+			}
+		}
+		// Mech World counts as every faction once its request is answered
+		if key == "mechWorld" && state.actionRequest(player).Action != ActivateMechWorld {
+			for faction := range m.allyState.flags {
+				m.allyState.flags[faction] = true
+				m.allyState.abilities[faction] = []*CardAbility{}
+			}
+		}
+	}
+}
+
+// newScenarioHub makes the hub starting from the scenario, the scenario is
+// loaded into a throwaway state first so the hub doesn't start broken
+func newScenarioHub(name string, scenario *Scenario, store *Store) (*Hub, error) {
+	err := scenario.resolve()
+	if err != nil {
+		return nil, err
+	}
+	deck := gameDeck(scenario.Options)
+	err = scenario.load(newState(deck, scenario.Options), newMiddleware(deck, scenario.Options))
+	if err != nil {
+		return nil, err
+	}
+	hub := newHub(name, scenario.Options, store)
+	hub.scenario = scenario
+	// Set up for testing, not listed for the players
+	hub.access, err = newHubAccess(Unlisted, "")
+	if err != nil {
+		return nil, err
+	}
+	return hub, nil
+}
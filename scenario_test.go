@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// ruleStep is a message of a player as it comes from the client
+type ruleStep struct {
+	player  PlayerId
+	message string
+}
+
+// ruleCase starts a two player game from the piles and the counters, plays
+// the steps and checks only the expectations which are given
+type ruleCase struct {
+	name     string
+	piles    map[string][]string
+	counters map[PlayerId]Counters
+	requests map[PlayerId]ActionRequest
+	// The player of the turn the steps start in, the first one by default
+	startTurn PlayerId
+	// Activated abilities available before the steps
+	activated map[string]ActivatedAbilities
+	steps     []ruleStep
+
+	// Location names of the cards
+	locations map[string]string
+	// Top cards of the locations
+	tops map[string]string
+	// Number of the cards in the locations
+	sizes        map[string]int
+	want         map[PlayerId]Counters
+	turn         PlayerId
+	wantRequests map[PlayerId]UserAction
+	// Only the listed abilities of the listed cards are compared
+	abilities map[string]ActivatedAbilities
+}
+
+func play(id string) ruleStep {
+	return ruleStep{FirstPlayer, fmt.Sprintf("%d,%s", Play, id)}
+}
+
+func activate(id string, ability AbilityId) ruleStep {
+	return ruleStep{FirstPlayer, fmt.Sprintf("%d,%s,%d", ActivateAbility, id, ability)}
+}
+
+func respond(action UserAction, args ...string) ruleStep {
+	return ruleStep{FirstPlayer, strings.Join(append([]string{fmt.Sprint(int(action))}, args...), ",")}
+}
+
+func by(player PlayerId, step ruleStep) ruleStep {
+	step.player = player
+	return step
+}
+
+func counters(trade int, combat int, authority int) Counters {
+	return Counters{Trade: trade, Combat: combat, Authority: authority}
+}
+
+// runScenario plays the steps from the scenario the way the hub does and
+// returns the state once every action is applied
+func runScenario(t *testing.T, scenario Scenario, steps []ruleStep) *StateManager {
+	t.Helper()
+	err := scenario.resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	deck := gameDeck(scenario.Options)
+	stateManager := newStateManager(deck, scenario.Options)
+	middleware := newMiddleware(deck, scenario.Options)
+	err = scenario.load(stateManager.state, middleware)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go stateManager.run()
+	go func() {
+		for range stateManager.updates {
+		}
+	}()
+	for _, step := range steps {
+		// The refused steps leave the state as it is
+		actions, _ := middleware.handle(step.message, step.player, stateManager.state)
+		for _, action := range actions {
+			stateManager.action <- action
+		}
+	}
+	version := make(chan int)
+	stateManager.action <- &StateActionGetVersion{version: version}
+	<-version
+	return stateManager
+}
+
+func runRuleCase(t *testing.T, c ruleCase) {
+	scenario := Scenario{
+		Options:            GameOptions{Players: MinPlayers, Seed: 1},
+		Piles:              c.piles,
+		Counters:           c.counters,
+		Turn:               c.startTurn,
+		ActionRequests:     c.requests,
+		ActivatedAbilities: c.activated,
+	}
+	stateManager := runScenario(t, scenario, c.steps)
+	state := stateManager.state
+
+	for id, name := range c.locations {
+		location, _ := locationByName(name)
+		card, ok := state.Cards[id]
+		if !ok {
+			t.Errorf("unknown card %s", id)
+			continue
+		}
+		if card.Location != location {
+			t.Errorf("%s is in %s, want %s", id, locationNames[card.Location], name)
+		}
+	}
+	for name, id := range c.tops {
+		location, _ := locationByName(name)
+		if top, _ := state.pile(location).top(); top != id {
+			t.Errorf("top of %s is %q, want %q", name, top, id)
+		}
+	}
+	for name, size := range c.sizes {
+		location, _ := locationByName(name)
+		if len(state.pile(location)) != size {
+			t.Errorf("%s has %d cards, want %d", name, len(state.pile(location)), size)
+		}
+	}
+	for player, want := range c.want {
+		got := *state.counters(player)
+		got.fleetFlag, got.blobs = 0, 0
+		if got != want {
+			t.Errorf("counters of player %d are %+v, want %+v", player, got, want)
+		}
+	}
+	if c.turn != 0 && state.Turn != c.turn {
+		t.Errorf("turn of player %d, want %d", state.Turn, c.turn)
+	}
+	for player, want := range c.wantRequests {
+		if got := state.actionRequest(player).Action; got != want {
+			t.Errorf("player %d is requested %s, want %s", player, userActionNames[got], userActionNames[want])
+		}
+	}
+	for id, abilities := range c.abilities {
+		for ability, want := range abilities {
+			if got := state.ActivatedAbilities[id][ability]; got != want {
+				t.Errorf("ability %d of %s is %t, want %t", ability, id, got, want)
+			}
+		}
+	}
+	for _, violation := range state.violations(stateManager.totalCards) {
+		t.Error(violation)
+	}
+}
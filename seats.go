@@ -0,0 +1,250 @@
+package main
+
+import "fmt"
+
+const (
+	MinPlayers int = 2
+	MaxPlayers int = 4
+	// Team formats are played by two teams of two players
+	TeamPlayers int = 4
+)
+
+const (
+	HydraAuthority        int = 75
+	EmperorAuthorityBonus int = 10
+)
+
+type GameFormat int
+
+const (
+	// Every player may attack any opponent
+	FreeForAll GameFormat = iota
+	// Every player may attack only the player to the left
+	Hunter
+	// Every player may attack only the player to the right
+	Vulture
+	// Teammates share authority and counters and take a shared turn
+	Hydra
+	// The team loses as soon as its emperor is eliminated
+	Emperor
+)
+
+type Team int
+
+const (
+	NoTeam Team = iota
+	FirstTeam
+	SecondTeam
+)
+
+// SeatLocations are the card locations owned by one seat
+type SeatLocations struct {
+	Deck    CardLocation
+	Hand    CardLocation
+	Table   CardLocation
+	Discard CardLocation
+	Bases   CardLocation
+	Gambits CardLocation
+}
+
+var seatLocations = map[PlayerId]SeatLocations{
+	FirstPlayer: SeatLocations{
+		Deck:    FirstPlayerDeck,
+		Hand:    FirstPlayerHand,
+		Table:   FirstPlayerTable,
+		Discard: FirstPlayerDiscard,
+		Bases:   FirstPlayerBases,
+		Gambits: FirstPlayerGambits,
+	},
+	SecondPlayer: SeatLocations{
+		Deck:    SecondPlayerDeck,
+		Hand:    SecondPlayerHand,
+		Table:   SecondPlayerTable,
+		Discard: SecondPlayerDiscard,
+		Bases:   SecondPlayerBases,
+		Gambits: SecondPlayerGambits,
+	},
+	ThirdPlayer: SeatLocations{
+		Deck:    ThirdPlayerDeck,
+		Hand:    ThirdPlayerHand,
+		Table:   ThirdPlayerTable,
+		Discard: ThirdPlayerDiscard,
+		Bases:   ThirdPlayerBases,
+		Gambits: ThirdPlayerGambits,
+	},
+	FourthPlayer: SeatLocations{
+		Deck:    FourthPlayerDeck,
+		Hand:    FourthPlayerHand,
+		Table:   FourthPlayerTable,
+		Discard: FourthPlayerDiscard,
+		Bases:   FourthPlayerBases,
+		Gambits: FourthPlayerGambits,
+	},
+}
+
+type WrongGameOptionsError struct {
+	reason string
+}
+
+func (e *WrongGameOptionsError) Error() string {
+	return fmt.Sprintf("wrong game options: %s", e.reason)
+}
+
+func validateSeating(players int, format GameFormat) error {
+	if players < MinPlayers || players > MaxPlayers {
+		return &WrongGameOptionsError{fmt.Sprintf("players should be from %d to %d", MinPlayers, MaxPlayers)}
+	}
+	if format < FreeForAll || format > Emperor {
+		return &WrongGameOptionsError{fmt.Sprintf("unknown format %d", format)}
+	}
+	if isTeamFormat(format) && players != TeamPlayers {
+		return &WrongGameOptionsError{fmt.Sprintf("team formats require %d players", TeamPlayers)}
+	}
+	return nil
+}
+
+func isTeamFormat(format GameFormat) bool {
+	return format == Hydra || format == Emperor
+}
+
+// teamOf returns the team of the seat, teammates are sitting across the
+// table so the turns alternate between the teams
+func teamOf(state *State, player PlayerId) Team {
+	if !isTeamFormat(state.Format) || !isSeated(state, player) {
+		return NoTeam
+	}
+	if player%2 == 1 {
+		return FirstTeam
+	}
+	return SecondTeam
+}
+
+func teamPlayers(state *State, team Team) []PlayerId {
+	players := []PlayerId{}
+	for i := 1; i <= state.Players; i++ {
+		if teamOf(state, PlayerId(i)) == team {
+			players = append(players, PlayerId(i))
+		}
+	}
+	return players
+}
+
+// teamLead is the first seat of the team: the emperor in the Emperor format
+// and the seat holding the shared turn in the Hydra format
+func teamLead(team Team) PlayerId {
+	return PlayerId(team)
+}
+
+func isEmperor(state *State, player PlayerId) bool {
+	return state.Format == Emperor && teamLead(teamOf(state, player)) == player
+}
+
+// ownerOf returns the seat owning the location, common locations such as
+// TradeRow have no owner
+func ownerOf(location CardLocation) (PlayerId, bool) {
+	for player, locations := range seatLocations {
+		switch location {
+		case locations.Deck,
+			locations.Hand,
+			locations.Table,
+			locations.Discard,
+			locations.Bases,
+			locations.Gambits:
+			return player, true
+		}
+	}
+	return 0, false
+}
+
+func isHand(location CardLocation) bool {
+	player, ok := ownerOf(location)
+	return ok && seatLocations[player].Hand == location
+}
+
+func isSeated(state *State, player PlayerId) bool {
+	return player >= FirstPlayer && int(player) <= state.Players
+}
+
+func isActive(state *State, player PlayerId) bool {
+	if !isSeated(state, player) {
+		return false
+	}
+	return !state.counters(player).Eliminated
+}
+
+func activePlayers(state *State) []PlayerId {
+	players := []PlayerId{}
+	for i := 1; i <= state.Players; i++ {
+		if isActive(state, PlayerId(i)) {
+			players = append(players, PlayerId(i))
+		}
+	}
+	return players
+}
+
+// nextPlayer returns the closest active seat to the left, turns are passed
+// in this direction
+func nextPlayer(state *State, player PlayerId) PlayerId {
+	next := player
+	for i := 0; i < state.Players; i++ {
+		next = next%PlayerId(state.Players) + 1
+		if isActive(state, next) {
+			return next
+		}
+	}
+	return player
+}
+
+// previousPlayer returns the closest active seat to the right
+func previousPlayer(state *State, player PlayerId) PlayerId {
+	previous := player
+	for i := 0; i < state.Players; i++ {
+		previous = (previous+PlayerId(state.Players)-2)%PlayerId(state.Players) + 1
+		if isActive(state, previous) {
+			return previous
+		}
+	}
+	return player
+}
+
+// defaultTarget is the opponent affected by the abilities without an
+// explicit target
+func defaultTarget(state *State, player PlayerId) PlayerId {
+	if state.Format == Vulture {
+		return previousPlayer(state, player)
+	}
+	return nextPlayer(state, player)
+}
+
+// nextTurn returns the seat holding the next turn, in the Hydra format the
+// turn is held by the lead seat of the team
+func nextTurn(state *State, player PlayerId) PlayerId {
+	if state.Format == Hydra {
+		return teamLead(teamOf(state, nextPlayer(state, teamLead(teamOf(state, player)))))
+	}
+	return nextPlayer(state, player)
+}
+
+// turnPlayers returns the seats acting during the turn held by the player
+func turnPlayers(state *State, player PlayerId) []PlayerId {
+	if state.Format == Hydra {
+		return teamPlayers(state, teamOf(state, player))
+	}
+	return []PlayerId{player}
+}
+
+func canAttack(state *State, attacker PlayerId, target PlayerId) bool {
+	if attacker == target || !isActive(state, attacker) || !isActive(state, target) {
+		return false
+	}
+	switch state.Format {
+	case Hydra, Emperor:
+		return teamOf(state, attacker) != teamOf(state, target)
+	case Hunter:
+		return target == nextPlayer(state, attacker)
+	case Vulture:
+		return target == previousPlayer(state, attacker)
+	default:
+		return true
+	}
+}
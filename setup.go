@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+const (
+	InitialAuthority int = 50
+	MaxAuthority     int = 200
+	MaxHandSize      int = 10
+	MaxTradeRowSize  int = 10
+	MaxStartingDeck  int = 30
+)
+
+// Setup is the starting position of the game, resolveSetup fills the values
+// which are left out with the defaults of the rules
+type Setup struct {
+	// Starting authority of every seat, the team lead's one in Hydra
+	Authority []int `json:"authority"`
+	// Size of the first hand of every seat
+	HandSizes []int `json:"handSizes"`
+	// Cards drawn at the end of every turn
+	HandSize     int      `json:"handSize"`
+	TradeRowSize int      `json:"tradeRowSize"`
+	FirstPlayer  PlayerId `json:"firstPlayer"`
+	// Picks the first player with the seed of the game
+	RandomFirstPlayer bool `json:"randomFirstPlayer,omitempty"`
+	// Quantities of the cards by id, the cards which aren't listed keep the
+	// quantities of the deck
+	CardPool map[string]int `json:"cardPool,omitempty"`
+	// Cards of the deck every seat starts with
+	StartingDeck map[string]int `json:"startingDeck"`
+}
+
+var defaultStartingDeck = map[string]int{
+	"scout": 8,
+	"viper": 2,
+}
+
+// resolveSetup validates the setup of the options and fills it up, it may
+// be called again on a resolved setup
+func resolveSetup(options *GameOptions) error {
+	setup := &options.Setup
+	players := options.Players
+
+	deck := gameDeck(*options)
+	for key, qty := range setup.CardPool {
+		card, ok := (*deck)[key]
+		if !ok {
+			return &WrongGameOptionsError{fmt.Sprintf("unknown card %q", key)}
+		}
+		if qty < 0 || (card.cardType == Gambit && qty > 1) {
+			return &WrongGameOptionsError{fmt.Sprintf("wrong quantity of %q", key)}
+		}
+	}
+
+	if setup.FirstPlayer == 0 {
+		setup.FirstPlayer = FirstPlayer
+		// The player always goes first against a challenge
+		if setup.RandomFirstPlayer && options.Challenge == "" {
+			candidates := players
+			// The shared turns of Hydra are held by the team leads
+			if options.Format == Hydra {
+				candidates = 2
+			}
+			random := rand.New(rand.NewSource(options.Seed))
+			setup.FirstPlayer = PlayerId(random.Intn(candidates) + 1)
+		}
+	}
+	if setup.FirstPlayer < FirstPlayer || int(setup.FirstPlayer) > players {
+		return &WrongGameOptionsError{fmt.Sprintf("no seat %d", setup.FirstPlayer)}
+	}
+	if options.Format == Hydra && setup.FirstPlayer != teamLead(FirstTeam) && setup.FirstPlayer != teamLead(SecondTeam) {
+		return &WrongGameOptionsError{"a team lead has to go first in Hydra"}
+	}
+	if options.Challenge != "" && setup.FirstPlayer == ChallengeSeat {
+		return &WrongGameOptionsError{"the player has to go first in a challenge"}
+	}
+
+	if setup.Authority == nil {
+		setup.Authority = make([]int, players)
+		for i := range setup.Authority {
+			setup.Authority[i] = InitialAuthority
+			if options.Format == Hydra {
+				setup.Authority[i] = HydraAuthority
+			}
+		}
+	}
+	if len(setup.Authority) != players {
+		return &WrongGameOptionsError{fmt.Sprintf("authority should be given for %d seats", players)}
+	}
+	for _, authority := range setup.Authority {
+		if authority < 1 || authority > MaxAuthority {
+			return &WrongGameOptionsError{fmt.Sprintf("authority should be from 1 to %d", MaxAuthority)}
+		}
+	}
+
+	if setup.HandSize == 0 {
+		setup.HandSize = HandCardsQty
+	}
+	if setup.HandSize < 1 || setup.HandSize > MaxHandSize {
+		return &WrongGameOptionsError{fmt.Sprintf("hand size should be from 1 to %d", MaxHandSize)}
+	}
+	if setup.HandSizes == nil {
+		setup.HandSizes = make([]int, players)
+		for i := 0; i < players; i++ {
+			// Counted from the first player
+			seat := (int(setup.FirstPlayer)-1+i)%players + 1
+			switch {
+			case i == 0:
+				setup.HandSizes[seat-1] = FirstPlayerHandCardsQty
+			case i == 1 && players > 2:
+				setup.HandSizes[seat-1] = SecondPlayerHandCardsQty
+			default:
+				setup.HandSizes[seat-1] = setup.HandSize
+			}
+		}
+	}
+	if len(setup.HandSizes) != players {
+		return &WrongGameOptionsError{fmt.Sprintf("hand sizes should be given for %d seats", players)}
+	}
+	for _, size := range setup.HandSizes {
+		if size < 0 || size > MaxHandSize {
+			return &WrongGameOptionsError{fmt.Sprintf("hand sizes should be from 0 to %d", MaxHandSize)}
+		}
+	}
+
+	if setup.TradeRowSize == 0 {
+		setup.TradeRowSize = TradeRowQty
+	}
+	if setup.TradeRowSize < 1 || setup.TradeRowSize > MaxTradeRowSize {
+		return &WrongGameOptionsError{fmt.Sprintf("trade row size should be from 1 to %d", MaxTradeRowSize)}
+	}
+
+	if setup.StartingDeck == nil {
+		setup.StartingDeck = make(map[string]int)
+		for key, qty := range defaultStartingDeck {
+			setup.StartingDeck[key] = qty
+		}
+	}
+	total := 0
+	for key, qty := range setup.StartingDeck {
+		card, ok := (*deck)[key]
+		if !ok || card.cardType == Gambit {
+			return &WrongGameOptionsError{fmt.Sprintf("unknown card %q in the starting deck", key)}
+		}
+		if qty < 0 {
+			return &WrongGameOptionsError{fmt.Sprintf("wrong quantity of %q in the starting deck", key)}
+		}
+		total += qty
+	}
+	if total < 1 || total > MaxStartingDeck {
+		return &WrongGameOptionsError{fmt.Sprintf("the starting deck should have from 1 to %d cards", MaxStartingDeck)}
+	}
+	return nil
+}
+
+// isStartingCard tells the cards which are dealt only to the starting decks
+func isStartingCard(key string) bool {
+	_, ok := defaultStartingDeck[key]
+	return ok
+}
+
+func sortedCardKeys(deck *map[string]*CardEntry) []string {
+	keys := make([]string, 0, len(*deck))
+	for key := range *deck {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+// The random first player is picked among the seats allowed to go first
+func TestRandomFirstPlayer(t *testing.T) {
+	cases := []struct {
+		name    string
+		options GameOptions
+		allowed map[PlayerId]bool
+	}{
+		{"free for all", GameOptions{Players: 4}, map[PlayerId]bool{FirstPlayer: true, SecondPlayer: true, ThirdPlayer: true, FourthPlayer: true}},
+		{"hydra", GameOptions{Players: TeamPlayers, Format: Hydra}, map[PlayerId]bool{FirstPlayer: true, SecondPlayer: true}},
+		{"challenge", GameOptions{Players: MinPlayers, Challenge: "raider"}, map[PlayerId]bool{FirstPlayer: true}},
+	}
+	for _, c := range cases {
+		for seed := int64(0); seed < 20; seed++ {
+			options := c.options
+			options.Seed = seed
+			options.Setup.RandomFirstPlayer = true
+			err := resolveSetup(&options)
+			if err != nil {
+				t.Fatalf("%s with seed %d: %v", c.name, seed, err)
+			}
+			if !c.allowed[options.Setup.FirstPlayer] {
+				t.Errorf("%s with seed %d: player %d goes first", c.name, seed, options.Setup.FirstPlayer)
+			}
+		}
+	}
+}
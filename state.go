@@ -3,21 +3,39 @@ package main
 import "fmt"
 
 type State struct {
+	Players                   int                           `json:"players"`
+	Format                    GameFormat                    `json:"format"`
+	Setup                     Setup                         `json:"setup"`
 	Turn                      PlayerId                      `json:"turn"`
+	Winner                    PlayerId                      `json:"winner"`
+	WinningTeam               Team                          `json:"winningTeam"`
 	FirstPlayerCounters       Counters                      `json:"firstPlayerCounters"`
 	SecondPlayerCounters      Counters                      `json:"secondPlayerCounters"`
+	ThirdPlayerCounters       Counters                      `json:"thirdPlayerCounters"`
+	FourthPlayerCounters      Counters                      `json:"fourthPlayerCounters"`
+	FirstTeamCounters         Counters                      `json:"firstTeamCounters"`
+	SecondTeamCounters        Counters                      `json:"secondTeamCounters"`
 	Cards                     map[string]*Card              `json:"cards"`
 	FirstPlayerActionRequest  ActionRequest                 `json:"firstPlayerActionRequest"`
 	SecondPlayerActionRequest ActionRequest                 `json:"secondPlayerActionRequest"`
+	ThirdPlayerActionRequest  ActionRequest                 `json:"thirdPlayerActionRequest"`
+	FourthPlayerActionRequest ActionRequest                 `json:"fourthPlayerActionRequest"`
 	ActivatedAbilities        map[string]ActivatedAbilities `json:"activatedAbilities"`
-	Actions                   []map[string]interface{}      `json:"actions"`
-	lastIndex                 map[CardLocation]int
+	Actions                   []ActionMessage               `json:"actions"`
+	Log                       []LogEntry                    `json:"log"`
+	UndoDisabled              bool                          `json:"undoDisabled"`
+	// The seats which don't let the others undo
+	UndoRefused []PlayerId `json:"undoRefused"`
+	// The order of the cards of every location, Cards keeps the location
+	// and the position of every card for the clients
+	piles map[CardLocation]Pile
 }
 type ActivatedAbilities map[AbilityId]bool
 
 type Card struct {
 	Location CardLocation `json:"location"`
-	Index    int          `json:"index"`
+	// Position in the pile of the location counted from 1 at the bottom
+	Index int `json:"index"`
 }
 
 type CardLocation int
@@ -38,14 +56,30 @@ const (
 	SecondPlayerTable
 	SecondPlayerDiscard
 	SecondPlayerBases
+	GambitDeck
+	FirstPlayerGambits
+	SecondPlayerGambits
+	ThirdPlayerDeck
+	ThirdPlayerHand
+	ThirdPlayerTable
+	ThirdPlayerDiscard
+	ThirdPlayerBases
+	ThirdPlayerGambits
+	FourthPlayerDeck
+	FourthPlayerHand
+	FourthPlayerTable
+	FourthPlayerDiscard
+	FourthPlayerBases
+	FourthPlayerGambits
 )
 
 type Counters struct {
-	Trade      int `json:"trade"`
-	Combat     int `json:"combat"`
-	Authority  int `json:"authority"`
-	Discard    int `json:"discard"`
-	ShipsOnTop int `json:"shipsOnTop"`
+	Trade      int  `json:"trade"`
+	Combat     int  `json:"combat"`
+	Authority  int  `json:"authority"`
+	Discard    int  `json:"discard"`
+	ShipsOnTop int  `json:"shipsOnTop"`
+	Eliminated bool `json:"eliminated"`
 	fleetFlag  int
 	blobs      int
 }
@@ -73,6 +107,8 @@ const (
 	ActivateMechWorld
 	ActivateRecyclingStation
 	ActivateNeedle
+	Undo
+	DisableUndo
 )
 
 type ActionRequest struct {
@@ -80,85 +116,144 @@ type ActionRequest struct {
 	CardId string     `json:"cardId"`
 }
 
-func newState(deck *map[string]*CardEntry) *State {
-	const initialAuthority int = 50
-	lastIndex := make(map[CardLocation]int)
-	cards := cardsInitialSet(deck, lastIndex)
-	return &State{
-		Turn: FirstPlayer,
-		FirstPlayerCounters: Counters{
-			Authority: initialAuthority,
-		},
-		SecondPlayerCounters: Counters{
-			Authority: initialAuthority,
-		},
-		Cards: cards,
+type GameOptions struct {
+	Gambits   bool       `json:"gambits"`
+	Players   int        `json:"players"`
+	Format    GameFormat `json:"format"`
+	Challenge string     `json:"challenge"`
+	// Decides every shuffle of the game
+	Seed int64 `json:"seed"`
+	// The results of the unrated games don't change the ratings
+	Unrated bool `json:"unrated"`
+	// Chosen for the competitive games, nobody may undo then
+	NoUndo bool  `json:"noUndo"`
+	Setup  Setup `json:"setup"`
+}
+
+func newState(deck *map[string]*CardEntry, options GameOptions) *State {
+	piles := make(map[CardLocation]Pile)
+	cards := cardsInitialSet(deck, options.Setup.StartingDeck, piles, options.Players)
+	state := &State{
+		Players:                   options.Players,
+		Format:                    options.Format,
+		Setup:                     options.Setup,
+		Turn:                      options.Setup.FirstPlayer,
+		Cards:                     cards,
 		FirstPlayerActionRequest:  ActionRequest{},
 		SecondPlayerActionRequest: ActionRequest{},
+		ThirdPlayerActionRequest:  ActionRequest{},
+		FourthPlayerActionRequest: ActionRequest{},
 		ActivatedAbilities:        make(map[string]ActivatedAbilities),
-		lastIndex:                 lastIndex,
+		UndoDisabled:              options.NoUndo,
+		piles:                     piles,
+	}
+	for i := 1; i <= options.Players; i++ {
+		// Teammates share the counters of the team lead in Hydra
+		if options.Format == Hydra && teamLead(teamOf(state, PlayerId(i))) != PlayerId(i) {
+			continue
+		}
+		state.counters(PlayerId(i)).Authority = options.Setup.Authority[i-1]
+		if isEmperor(state, PlayerId(i)) {
+			state.counters(PlayerId(i)).Authority += EmperorAuthorityBonus
+		}
 	}
+	return state
 }
 
-func cardsInitialSet(deck *map[string]*CardEntry, lastIndex map[CardLocation]int) map[string]*Card {
-	cards := make(map[string]*Card)
-	for key, card := range *deck {
-		switch key {
-		case "scout":
-			h := card.qty / 2
-			for i := 1; i <= card.qty; i++ {
-				id := fmt.Sprintf("%s_%d", key, i)
-				if i <= h {
-					lastIndex[FirstPlayerDeck] += 1
-					cards[id] = &Card{
-						Location: FirstPlayerDeck,
-						Index:    lastIndex[FirstPlayerDeck],
-					}
-				} else {
-					lastIndex[SecondPlayerDeck] += 1
-					cards[id] = &Card{
-						Location: SecondPlayerDeck,
-						Index:    lastIndex[SecondPlayerDeck],
-					}
-				}
-			}
-		case "viper":
-			h := card.qty / 2
-			for i := 1; i <= card.qty; i++ {
-				id := fmt.Sprintf("%s_%d", key, i)
-				if i <= h {
-					lastIndex[FirstPlayerDeck] += 1
-					cards[id] = &Card{
-						Location: FirstPlayerDeck,
-						Index:    lastIndex[FirstPlayerDeck],
-					}
-				} else {
-					lastIndex[SecondPlayerDeck] += 1
-					cards[id] = &Card{
-						Location: SecondPlayerDeck,
-						Index:    lastIndex[SecondPlayerDeck],
-					}
-				}
-			}
-		case "explorer":
-			for i := 1; i <= card.qty; i++ {
-				lastIndex[Explorers] += 1
-				id := fmt.Sprintf("%s_%d", key, i)
-				cards[id] = &Card{
-					Location: Explorers,
-					Index:    lastIndex[Explorers],
-				}
-			}
-		default:
-			for i := 1; i <= card.qty; i++ {
-				id := fmt.Sprintf("%s_%d", key, i)
-				lastIndex[TradeDeck] += 1
-				cards[id] = &Card{
-					Location: TradeDeck,
-					Index:    lastIndex[TradeDeck],
-				}
-			}
+// counters returns the counters of the seat or nil for unknown seats, in the
+// Hydra format teammates share the team counters
+func (s *State) counters(player PlayerId) *Counters {
+	if s.Format == Hydra {
+		switch teamOf(s, player) {
+		case FirstTeam:
+			return &s.FirstTeamCounters
+		case SecondTeam:
+			return &s.SecondTeamCounters
+		}
+	}
+	switch player {
+	case FirstPlayer:
+		return &s.FirstPlayerCounters
+	case SecondPlayer:
+		return &s.SecondPlayerCounters
+	case ThirdPlayer:
+		return &s.ThirdPlayerCounters
+	case FourthPlayer:
+		return &s.FourthPlayerCounters
+	default:
+		return nil
+	}
+}
+
+// actionRequest returns the action request of the seat or nil for unknown
+// seats
+func (s *State) actionRequest(player PlayerId) *ActionRequest {
+	switch player {
+	case FirstPlayer:
+		return &s.FirstPlayerActionRequest
+	case SecondPlayer:
+		return &s.SecondPlayerActionRequest
+	case ThirdPlayer:
+		return &s.ThirdPlayerActionRequest
+	case FourthPlayer:
+		return &s.FourthPlayerActionRequest
+	default:
+		return nil
+	}
+}
+
+// clone makes a deep copy of the state
+func (s *State) clone() *State {
+	clone := *s
+	clone.Cards = make(map[string]*Card)
+	for id, card := range s.Cards {
+		c := *card
+		clone.Cards[id] = &c
+	}
+	clone.ActivatedAbilities = make(map[string]ActivatedAbilities)
+	for id, abilities := range s.ActivatedAbilities {
+		clone.ActivatedAbilities[id] = make(ActivatedAbilities)
+		for abilityId, value := range abilities {
+			clone.ActivatedAbilities[id][abilityId] = value
+		}
+	}
+	clone.Actions = append([]ActionMessage{}, s.Actions...)
+	clone.Log = append([]LogEntry{}, s.Log...)
+	clone.UndoRefused = append([]PlayerId{}, s.UndoRefused...)
+	clone.piles = s.clonePiles()
+	return &clone
+}
 
+func cardsInitialSet(deck *map[string]*CardEntry, startingDeck map[string]int, piles map[CardLocation]Pile, players int) map[string]*Card {
+	cards := make(map[string]*Card)
+	add := func(id string, location CardLocation) {
+		piles[location] = append(piles[location], id)
+		cards[id] = &Card{
+			Location: location,
+			Index:    len(piles[location]),
+		}
+	}
+	// Sorted to deal the same piles for the same seed
+	for _, key := range sortedCardKeys(deck) {
+		card := (*deck)[key]
+		location := TradeDeck
+		switch {
+		case key == "explorer":
+			location = Explorers
+		case card.cardType == Gambit:
+			location = GambitDeck
+		}
+		qty := card.qty
+		if isStartingCard(key) {
+			qty = 0
+		}
+		for i := 1; i <= qty; i++ {
+			add(fmt.Sprintf("%s_%d", key, i), location)
+		}
+		// The copies of the starting decks are numbered after the others
+		perSeat := startingDeck[key]
+		for i := 1; i <= perSeat*players; i++ {
+			add(fmt.Sprintf("%s_%d", key, qty+i), seatLocations[PlayerId((i-1)/perSeat+1)].Deck)
 		}
 	}
 	return cards
@@ -1,15 +1,35 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"log/slog"
 	"math/rand"
-	"time"
+	"runtime/debug"
 )
 
 type StateManager struct {
-	state  *State
-	action chan StateAction
-	json   chan []byte
+	state   *State
+	action  chan StateAction
+	updates chan StateUpdate
+	random  *rand.Rand
+
+	// Incremented on every broadcast changing the state
+	version int
+	// The last broadcast state decoded, patches are made against it
+	document interface{}
+
+	// Verifies the state after every action, see checkState
+	check      bool
+	halt       bool
+	halted     bool
+	totalCards int
+	violations map[string]bool
+	// Told when the game halts so the hub rejects the actions
+	onHalt func()
+
+	logger *slog.Logger
 }
 
 type StateActionType int
@@ -27,6 +47,13 @@ const (
 	ResetActivatedAbilities
 	ResetActions
 	ShuffleDeck
+	EliminatePlayer
+	GameOver
+	RestoreState
+	DisableUndoAction
+	GetVersion
+	AddLogEntry
+	GetSnapshot
 )
 
 type PlayerId int
@@ -34,6 +61,8 @@ type PlayerId int
 const (
 	FirstPlayer PlayerId = iota + 1
 	SecondPlayer
+	ThirdPlayer
+	FourthPlayer
 )
 
 type Counter int
@@ -56,16 +85,40 @@ const (
 	Set
 )
 
-func EncodeAction(action StateAction) map[string]interface{} {
-	data := make(map[string]interface{})
-	data["type"] = action.Type()
-	data["data"] = action.Data()
-	return data
+// ActionMessage is an applied action as it's sent to the clients, the data
+// of every type is documented by the MarshalJSON method of its action
+type ActionMessage struct {
+	Type StateActionType `json:"type"`
+	Data StateAction     `json:"data"`
+}
+
+func EncodeAction(action StateAction) ActionMessage {
+	return ActionMessage{
+		Type: action.Type(),
+		Data: action,
+	}
+}
+
+func (a ActionMessage) String() string {
+	message, err := json.Marshal(a)
+	if err != nil {
+		return err.Error()
+	}
+	return string(message)
 }
 
 type StateAction interface {
 	Type() StateActionType
-	Data() map[string]interface{}
+	// apply changes the state of the manager, it's called only by run
+	apply(s *StateManager)
+	json.Marshaler
+}
+
+// noData is embedded by the actions which are sent without data
+type noData struct{}
+
+func (noData) MarshalJSON() ([]byte, error) {
+	return []byte("{}"), nil
 }
 
 type StateActionChangeCounterValue struct {
@@ -79,13 +132,31 @@ func (s *StateActionChangeCounterValue) Type() StateActionType {
 	return ChangeCounterValue
 }
 
-func (s *StateActionChangeCounterValue) Data() map[string]interface{} {
-	data := make(map[string]interface{})
-	data["player"] = s.player
-	data["counter"] = s.counter
-	data["operation"] = s.operation
-	data["value"] = s.value
-	return data
+func (s *StateActionChangeCounterValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Player    PlayerId  `json:"player"`
+		Counter   Counter   `json:"counter"`
+		Operation Operation `json:"operation"`
+		Value     int       `json:"value"`
+	}{s.player, s.counter, s.operation, s.value})
+}
+
+func (s *StateActionChangeCounterValue) apply(m *StateManager) {
+	c := m.state.counters(s.player)
+	if c == nil {
+		return
+	}
+	counters := make(map[Counter]*int)
+	counters[Trade] = &c.Trade
+	counters[Authority] = &c.Authority
+	counters[Combat] = &c.Combat
+	counters[Discard] = &c.Discard
+	counters[ShipsOnTop] = &c.ShipsOnTop
+	counters[fleetFlag] = &c.fleetFlag
+	counters[blobs] = &c.blobs
+	if counter, ok := counters[s.counter]; ok {
+		calc(counter, s.value, s.operation)
+	}
 }
 
 type StateActionTopCard struct {
@@ -97,11 +168,33 @@ func (s *StateActionTopCard) Type() StateActionType {
 	return TopCard
 }
 
-func (s *StateActionTopCard) Data() map[string]interface{} {
-	data := make(map[string]interface{})
-	data["from"] = s.from
-	data["to"] = s.to
-	return data
+func (s *StateActionTopCard) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		From CardLocation `json:"from"`
+		To   CardLocation `json:"to"`
+	}{s.from, s.to})
+}
+
+func (s *StateActionTopCard) apply(m *StateManager) {
+	owner, owned := ownerOf(s.from)
+	if len(m.state.piles[s.from]) == 0 && owned && seatLocations[owner].Deck == s.from {
+		m.moveAll(seatLocations[owner].Discard, s.from)
+		m.shuffle(s.from)
+	}
+	id, ok := m.state.piles[s.from].top()
+	if ok {
+		m.state.placeCard(id, s.to)
+		m.state.Actions = append(
+			m.state.Actions,
+			EncodeAction(
+				&StateActionMoveCard{
+					id:   id,
+					to:   s.to,
+					from: s.from,
+				},
+			),
+		)
+	}
 }
 
 type StateActionShuffleDeck struct {
@@ -112,10 +205,14 @@ func (s *StateActionShuffleDeck) Type() StateActionType {
 	return ShuffleDeck
 }
 
-func (s *StateActionShuffleDeck) Data() map[string]interface{} {
-	data := make(map[string]interface{})
-	data["deck"] = s.deck
-	return data
+func (s *StateActionShuffleDeck) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Deck CardLocation `json:"deck"`
+	}{s.deck})
+}
+
+func (s *StateActionShuffleDeck) apply(m *StateManager) {
+	m.shuffle(s.deck)
 }
 
 type StateActionMoveCard struct {
@@ -128,12 +225,16 @@ func (s *StateActionMoveCard) Type() StateActionType {
 	return MoveCard
 }
 
-func (s *StateActionMoveCard) Data() map[string]interface{} {
-	data := make(map[string]interface{})
-	data["id"] = s.id
-	data["to"] = s.to
-	data["from"] = s.from
-	return data
+func (s *StateActionMoveCard) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Id   string       `json:"id"`
+		To   CardLocation `json:"to"`
+		From CardLocation `json:"from"`
+	}{s.id, s.to, s.from})
+}
+
+func (s *StateActionMoveCard) apply(m *StateManager) {
+	m.state.placeCard(s.id, s.to)
 }
 
 type StateActionMoveAll struct {
@@ -145,33 +246,44 @@ func (s *StateActionMoveAll) Type() StateActionType {
 	return MoveAll
 }
 
-func (s *StateActionMoveAll) Data() map[string]interface{} {
-	data := make(map[string]interface{})
-	data["from"] = s.from
-	data["to"] = s.to
-	return data
+func (s *StateActionMoveAll) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		From CardLocation `json:"from"`
+		To   CardLocation `json:"to"`
+	}{s.from, s.to})
 }
 
-type StateActionGetState struct{}
+func (s *StateActionMoveAll) apply(m *StateManager) {
+	m.moveAll(s.from, s.to)
+}
+
+type StateActionGetState struct {
+	noData
+}
 
 func (s *StateActionGetState) Type() StateActionType {
 	return GetState
 }
 
-func (s *StateActionGetState) Data() map[string]interface{} {
-	data := make(map[string]interface{})
-	return data
+func (s *StateActionGetState) apply(m *StateManager) {
+	update, err := m.update()
+	if err != nil {
+		m.logger.Error("state not encoded", "err", err, "version", m.version)
+		return
+	}
+	m.updates <- update
 }
 
-type StateActionChangeTurn struct{}
+type StateActionChangeTurn struct {
+	noData
+}
 
 func (s *StateActionChangeTurn) Type() StateActionType {
 	return ChangeTurn
 }
 
-func (s *StateActionChangeTurn) Data() map[string]interface{} {
-	data := make(map[string]interface{})
-	return data
+func (s *StateActionChangeTurn) apply(m *StateManager) {
+	m.state.Turn = nextTurn(m.state, m.state.Turn)
 }
 
 type StateActionRequestUserAction struct {
@@ -184,12 +296,21 @@ func (s *StateActionRequestUserAction) Type() StateActionType {
 	return RequestUserAction
 }
 
-func (s *StateActionRequestUserAction) Data() map[string]interface{} {
-	data := make(map[string]interface{})
-	data["player"] = s.player
-	data["action"] = s.action
-	data["cardId"] = s.cardId
-	return data
+func (s *StateActionRequestUserAction) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Player PlayerId   `json:"player"`
+		Action UserAction `json:"action"`
+		CardId string     `json:"cardId"`
+	}{s.player, s.action, s.cardId})
+}
+
+func (s *StateActionRequestUserAction) apply(m *StateManager) {
+	if r := m.state.actionRequest(s.player); r != nil {
+		*r = ActionRequest{
+			Action: s.action,
+			CardId: s.cardId,
+		}
+	}
 }
 
 type StateActionAddActivatedAbility struct {
@@ -201,11 +322,20 @@ func (s *StateActionAddActivatedAbility) Type() StateActionType {
 	return AddActivatedAbility
 }
 
-func (s *StateActionAddActivatedAbility) Data() map[string]interface{} {
-	data := make(map[string]interface{})
-	data["cardId"] = s.cardId
-	data["abilityId"] = s.abilityId
-	return data
+func (s *StateActionAddActivatedAbility) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		CardId    string    `json:"cardId"`
+		AbilityId AbilityId `json:"abilityId"`
+	}{s.cardId, s.abilityId})
+}
+
+func (s *StateActionAddActivatedAbility) apply(m *StateManager) {
+	abilities, ok := m.state.ActivatedAbilities[s.cardId]
+	if !ok {
+		abilities = make(ActivatedAbilities)
+		m.state.ActivatedAbilities[s.cardId] = abilities
+	}
+	abilities[s.abilityId] = true
 }
 
 type StateActionDisableActivatedAbility struct {
@@ -217,258 +347,300 @@ func (s *StateActionDisableActivatedAbility) Type() StateActionType {
 	return DisableActivatedAbility
 }
 
-func (s *StateActionDisableActivatedAbility) Data() map[string]interface{} {
-	data := make(map[string]interface{})
-	data["cardId"] = s.cardId
-	data["abilityId"] = s.abilityId
-	return data
+func (s *StateActionDisableActivatedAbility) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		CardId    string    `json:"cardId"`
+		AbilityId AbilityId `json:"abilityId"`
+	}{s.cardId, s.abilityId})
 }
 
-type StateActionResetActivatedAbilities struct{}
+func (s *StateActionDisableActivatedAbility) apply(m *StateManager) {
+	abilities, ok := m.state.ActivatedAbilities[s.cardId]
+	if ok {
+		abilities[s.abilityId] = false
+	}
+}
+
+type StateActionResetActivatedAbilities struct {
+	noData
+}
 
 func (s *StateActionResetActivatedAbilities) Type() StateActionType {
 	return ResetActivatedAbilities
 }
 
-func (s *StateActionResetActivatedAbilities) Data() map[string]interface{} {
-	data := make(map[string]interface{})
-	return data
+func (s *StateActionResetActivatedAbilities) apply(m *StateManager) {
+	m.state.ActivatedAbilities = make(map[string]ActivatedAbilities)
 }
 
-type StateActionResetActions struct{}
+type StateActionResetActions struct {
+	noData
+}
 
 func (s *StateActionResetActions) Type() StateActionType {
 	return ResetActions
 }
 
-func (s *StateActionResetActions) Data() map[string]interface{} {
-	data := make(map[string]interface{})
-	return data
+func (s *StateActionResetActions) apply(m *StateManager) {
+	m.state.Actions = nil
+}
+
+type StateActionEliminatePlayer struct {
+	player PlayerId
+}
+
+func (s *StateActionEliminatePlayer) Type() StateActionType {
+	return EliminatePlayer
+}
+
+func (s *StateActionEliminatePlayer) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Player PlayerId `json:"player"`
+	}{s.player})
+}
+
+func (s *StateActionEliminatePlayer) apply(m *StateManager) {
+	if c := m.state.counters(s.player); c != nil {
+		c.Eliminated = true
+	}
+}
+
+type StateActionGameOver struct {
+	winner PlayerId
+	team   Team
+}
+
+func (s *StateActionGameOver) Type() StateActionType {
+	return GameOver
 }
 
-func newStateManager(deck *map[string]*CardEntry) *StateManager {
+func (s *StateActionGameOver) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Winner PlayerId `json:"winner"`
+		Team   Team     `json:"team"`
+	}{s.winner, s.team})
+}
+
+func (s *StateActionGameOver) apply(m *StateManager) {
+	m.state.Winner = s.winner
+	m.state.WinningTeam = s.team
+}
+
+// StateActionRestoreState is sent without data, the clients get the
+// restored state with the next update
+type StateActionRestoreState struct {
+	noData
+	state *State
+}
+
+func (s *StateActionRestoreState) Type() StateActionType {
+	return RestoreState
+}
+
+func (s *StateActionRestoreState) apply(m *StateManager) {
+	// The state is restored in place because the deferred calls of the
+	// middleware keep the pointer to it, undo stays off for the seats which
+	// turned it off since
+	refused := m.state.UndoRefused
+	*m.state = *s.state.clone()
+	m.state.UndoRefused = refused
+	m.state.Actions = []ActionMessage{EncodeAction(s)}
+}
+
+type StateActionDisableUndo struct {
+	player PlayerId
+}
+
+func (s *StateActionDisableUndo) Type() StateActionType {
+	return DisableUndoAction
+}
+
+func (s *StateActionDisableUndo) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Player PlayerId `json:"player"`
+	}{s.player})
+}
+
+func (s *StateActionDisableUndo) apply(m *StateManager) {
+	m.state.UndoRefused = append(m.state.UndoRefused, s.player)
+}
+
+// StateActionGetVersion replies with the version of the last broadcast
+// state. Being queued with the other actions it's replied only after all the
+// previously sent actions are applied.
+type StateActionGetVersion struct {
+	noData
+	version chan int
+}
+
+func (s *StateActionGetVersion) Type() StateActionType {
+	return GetVersion
+}
+
+func (s *StateActionGetVersion) apply(m *StateManager) {
+	// The caller waits for the reply, it's sent even if the step panics
+	defer func() { s.version <- m.version }()
+}
+
+// StateActionGetSnapshot replies with a copy of the state once the
+// previously sent actions are applied
+type StateActionGetSnapshot struct {
+	noData
+	snapshot chan *State
+}
+
+func (s *StateActionGetSnapshot) Type() StateActionType {
+	return GetSnapshot
+}
+
+func (s *StateActionGetSnapshot) apply(m *StateManager) {
+	var snapshot *State
+	// The caller waits for the reply, it gets nil if the copy panics
+	defer func() { s.snapshot <- snapshot }()
+	snapshot = m.state.clone()
+}
+
+type StateActionAddLogEntry struct {
+	entry LogEntry
+}
+
+func (s *StateActionAddLogEntry) Type() StateActionType {
+	return AddLogEntry
+}
+
+func (s *StateActionAddLogEntry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Entry LogEntry `json:"entry"`
+	}{s.entry})
+}
+
+func (s *StateActionAddLogEntry) apply(m *StateManager) {
+	m.state.Log = append(m.state.Log, s.entry)
+}
+
+func newStateManager(deck *map[string]*CardEntry, options GameOptions) *StateManager {
+	state := newState(deck, options)
 	return &StateManager{
-		state:  newState(deck),
-		action: make(chan StateAction),
-		json:   make(chan []byte),
+		state:      state,
+		action:     make(chan StateAction),
+		updates:    make(chan StateUpdate),
+		random:     rand.New(rand.NewSource(options.Seed)),
+		check:      config.CheckInvariants,
+		halt:       config.HaltOnViolation,
+		totalCards: len(state.Cards),
+		logger:     slog.Default(),
 	}
 }
 
 func (s *StateManager) run() {
 	for {
-		action := <-s.action
-		if action.Type() != GetState {
-			s.state.Actions = append(s.state.Actions, EncodeAction(action))
+		err := s.step(<-s.action)
+		if err != nil {
+			s.logger.Error("action not applied", "err", err, "version", s.version)
 		}
-		switch action.Type() {
-		case ChangeCounterValue:
-			data := action.Data()
-			player := data["player"].(PlayerId)
-			counter := data["counter"].(Counter)
-			operation := data["operation"].(Operation)
-			value := data["value"].(int)
-			c := &s.state.FirstPlayerCounters
-			if player == SecondPlayer {
-				c = &s.state.SecondPlayerCounters
-			}
-			counters := make(map[Counter]*int)
-			counters[Trade] = &c.Trade
-			counters[Authority] = &c.Authority
-			counters[Combat] = &c.Combat
-			counters[Discard] = &c.Discard
-			counters[ShipsOnTop] = &c.ShipsOnTop
-			counters[fleetFlag] = &c.fleetFlag
-			counters[blobs] = &c.blobs
-			calc(counters[counter], value, operation)
-		case TopCard:
-			data := action.Data()
-			from := data["from"].(CardLocation)
-			to := data["to"].(CardLocation)
-			deck := s.cardsByLocation(from)
-			if len(deck) == 0 && (from == FirstPlayerDeck || from == SecondPlayerDeck) {
-				if from == FirstPlayerDeck {
-					discard := s.cardsByLocation(FirstPlayerDiscard)
-					for id, c := range discard {
-						s.state.lastIndex[c.Location] -= 1
-						s.state.lastIndex[FirstPlayerDeck] += 1
-						c.Location = FirstPlayerDeck
-						c.Index = s.state.lastIndex[FirstPlayerDeck]
-						s.state.Actions = append(
-							s.state.Actions,
-							EncodeAction(
-								&StateActionMoveCard{
-									id:   id,
-									to:   FirstPlayerDeck,
-									from: from,
-								},
-							),
-						)
-					}
-				}
-				if from == SecondPlayerDeck {
-					discard := s.cardsByLocation(SecondPlayerDiscard)
-					for id, c := range discard {
-						s.state.lastIndex[c.Location] -= 1
-						s.state.lastIndex[SecondPlayerDeck] += 1
-						c.Location = SecondPlayerDeck
-						c.Index = s.state.lastIndex[SecondPlayerDeck]
-						s.state.Actions = append(
-							s.state.Actions,
-							EncodeAction(
-								&StateActionMoveCard{
-									id:   id,
-									to:   SecondPlayerDeck,
-									from: from,
-								},
-							),
-						)
-					}
-				}
-				deck = s.cardsByLocation(from)
-				s.shuffle(deck)
-			}
-			for id, card := range deck {
-				if card.Index == s.state.lastIndex[card.Location] {
-					s.state.lastIndex[card.Location] -= 1
-					s.state.lastIndex[to] += 1
-					card.Location = to
-					card.Index = s.state.lastIndex[to]
-					s.state.Actions = append(
-						s.state.Actions,
-						EncodeAction(
-							&StateActionMoveCard{
-								id:   id,
-								to:   to,
-								from: from,
-							},
-						),
-					)
-					break
-				}
-			}
-		case ShuffleDeck:
-			data := action.Data()
-			deck := data["deck"].(CardLocation)
-			s.shuffle(s.cardsByLocation(deck))
-		case MoveCard:
-			data := action.Data()
-			id := data["id"].(string)
-			to := data["to"].(CardLocation)
-			card, ok := s.cardById(id)
-			if ok {
-				if card.Location != TradeRow &&
-					card.Location != FirstPlayerHand &&
-					card.Location != SecondPlayerHand {
-					s.state.lastIndex[card.Location] -= 1
-				}
-				s.state.lastIndex[to] += 1
-				card.Location = to
-				card.Index = s.state.lastIndex[to]
-			}
-		case MoveAll:
-			data := action.Data()
-			from := data["from"].(CardLocation)
-			to := data["to"].(CardLocation)
-			cards := s.cardsByLocation(from)
-			for id, card := range cards {
-				s.state.lastIndex[card.Location] -= 1
-				s.state.lastIndex[to] += 1
-				card.Location = to
-				card.Index = s.state.lastIndex[to]
-				s.state.Actions = append(
-					s.state.Actions,
-					EncodeAction(
-						&StateActionMoveCard{
-							id:   id,
-							to:   to,
-							from: from,
-						},
-					),
-				)
-			}
-		case ChangeTurn:
-			if s.state.Turn == FirstPlayer {
-				s.state.Turn = SecondPlayer
-			} else {
-				s.state.Turn = FirstPlayer
-			}
-		case RequestUserAction:
-			data := action.Data()
-			player := data["player"].(PlayerId)
-			userAction := data["action"].(UserAction)
-			cardId := data["cardId"].(string)
-			actionRequest := ActionRequest{
-				Action: userAction,
-				CardId: cardId,
-			}
-			switch player {
-			case FirstPlayer:
-				s.state.FirstPlayerActionRequest = actionRequest
-			case SecondPlayer:
-				s.state.SecondPlayerActionRequest = actionRequest
-			}
-		case AddActivatedAbility:
-			data := action.Data()
-			cardId := data["cardId"].(string)
-			abilityId := data["abilityId"].(AbilityId)
-			abilities, ok := s.state.ActivatedAbilities[cardId]
-			if !ok {
-				s.state.ActivatedAbilities[cardId] = make(map[AbilityId]bool)
-				abilities, _ = s.state.ActivatedAbilities[cardId]
-			}
-			abilities[abilityId] = true
-		case DisableActivatedAbility:
-			data := action.Data()
-			cardId := data["cardId"].(string)
-			abilityId := data["abilityId"].(AbilityId)
-			abilities, ok := s.state.ActivatedAbilities[cardId]
-			if ok {
-				abilities[abilityId] = false
-			}
-		case ResetActivatedAbilities:
-			s.state.ActivatedAbilities = make(map[string]ActivatedAbilities)
-		case ResetActions:
-			var actions []map[string]interface{}
-			s.state.Actions = actions
-		case GetState:
-			state, _ := json.Marshal(s.state)
-			s.json <- state
+	}
+}
+
+// step applies the action, a panic of the action is recovered so that the
+// game goes on without it
+func (s *StateManager) step(action StateAction) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{fmt.Sprint(EncodeAction(action)), r, debug.Stack()}
 		}
+	}()
+	changesState := action.Type() != GetState && action.Type() != GetVersion && action.Type() != GetSnapshot
+	if changesState && s.halted {
+		s.logger.Warn("the game is halted, dropped action", "stateAction", EncodeAction(action), "version", s.version)
+		return nil
+	}
+	if changesState {
+		s.state.Actions = append(s.state.Actions, EncodeAction(action))
+	}
+	action.apply(s)
+	if changesState && s.logger.Enabled(context.Background(), slog.LevelDebug) {
+		s.logger.Debug("applied", "stateAction", EncodeAction(action), "version", s.version)
+	}
+	if changesState && s.check {
+		s.checkState(action)
 	}
+	return nil
 }
 
-func (s *StateManager) cardById(id string) (*Card, bool) {
-	card, ok := s.state.Cards[id]
-	return card, ok
+// PanicError is a recovered panic of the action of a game
+type PanicError struct {
+	action string
+	value  interface{}
+	stack  []byte
 }
 
-func (s *StateManager) cardsByLocation(l CardLocation) map[string]*Card {
-	result := make(map[string]*Card)
-	for id, card := range s.state.Cards {
-		if card.Location == l {
-			result[id] = card
-		}
-	}
-	return result
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic on action %s: %v\n%s", e.action, e.value, e.stack)
 }
 
-func (s *StateManager) shuffle(deck map[string]*Card) {
-	indexes := []int{}
-	for _, card := range deck {
-		indexes = append(indexes, card.Index)
+func (s *StateManager) update() (StateUpdate, error) {
+	state, err := json.Marshal(s.state)
+	if err != nil {
+		return StateUpdate{}, err
 	}
-	rand.Seed(time.Now().Unix())
-	for _, card := range deck {
-		idx := rand.Intn(len(indexes))
-		card.Index = indexes[idx]
-		indexes = removeFromSlice(indexes, idx)
+	var document interface{}
+	err = json.Unmarshal(state, &document)
+	if err != nil {
+		return StateUpdate{}, err
+	}
+	var operations []PatchOperation
+	if s.document != nil {
+		diff("", s.document, document, &operations)
+	}
+	if s.document == nil || len(operations) > 0 {
+		s.version += 1
+	}
+	s.document = document
+
+	update := StateUpdate{Version: s.version}
+	update.Snapshot, err = json.Marshal(SnapshotMessage{
+		Version: s.version,
+		State:   json.RawMessage(state),
+	})
+	if err != nil {
+		return StateUpdate{}, err
+	}
+	if len(operations) > 0 {
+		update.Patch, err = json.Marshal(PatchMessage{
+			Version: s.version,
+			Patch:   operations,
+		})
+		if err != nil {
+			return StateUpdate{}, err
+		}
+	}
+	return update, nil
+}
+
+// moveAll moves the cards from the bottom of the pile first so they keep
+// their order, every move is sent to the clients
+func (s *StateManager) moveAll(from CardLocation, to CardLocation) {
+	for _, id := range s.state.pile(from) {
+		s.state.placeCard(id, to)
+		s.state.Actions = append(
+			s.state.Actions,
+			EncodeAction(
+				&StateActionMoveCard{
+					id:   id,
+					to:   to,
+					from: from,
+				},
+			),
+		)
 	}
-
 }
 
-func removeFromSlice(s []int, i int) []int {
-	s[i] = s[len(s)-1]
-	return s[:len(s)-1]
+func (s *StateManager) shuffle(location CardLocation) {
+	pile := s.state.pile(location)
+	s.random.Shuffle(len(pile), func(i, j int) {
+		pile[i], pile[j] = pile[j], pile[i]
+	})
+	s.state.setPile(location, pile)
 }
 
 func calc(a *int, b int, operation Operation) {
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Store keeps the data which outlives the games in a local json file
+type Store struct {
+	mutex sync.Mutex
+	path  string
+	data  StoreData
+}
+
+type StoreData struct {
+	// Keyed by the lower case account id
+	Accounts map[string]*Account `json:"accounts"`
+	// Signs the session tokens
+	SessionSecret []byte `json:"sessionSecret"`
+}
+
+type ChallengeRecord struct {
+	Wins   int `json:"wins"`
+	Losses int `json:"losses"`
+}
+
+func newStore(path string) (*Store, error) {
+	store := &Store{
+		path: path,
+		data: StoreData{
+			Accounts: make(map[string]*Account),
+		},
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		err = json.Unmarshal(content, &store.data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if store.data.Accounts == nil {
+		store.data.Accounts = make(map[string]*Account)
+	}
+	if store.data.SessionSecret == nil {
+		store.data.SessionSecret, err = newSessionSecret()
+		if err != nil {
+			return nil, err
+		}
+		err = store.save()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+// save writes the data to a temporary file first so a crash can't leave
+// the store half written, the mutex has to be held by the caller
+func (s *Store) save() error {
+	content, err := json.Marshal(s.data)
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	err = ioutil.WriteFile(tmp, content, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// recordChallenge counts the game for the account, the games of the guests
+// aren't recorded
+func (s *Store) recordChallenge(accountId string, challengeId string, won bool) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	account, ok := s.data.Accounts[strings.ToLower(accountId)]
+	if !ok {
+		return nil
+	}
+	if account.Challenges == nil {
+		account.Challenges = make(map[string]*ChallengeRecord)
+	}
+	record, ok := account.Challenges[challengeId]
+	if !ok {
+		record = &ChallengeRecord{}
+		account.Challenges[challengeId] = record
+	}
+	if won {
+		record.Wins += 1
+	} else {
+		record.Losses += 1
+	}
+	return s.save()
+}
+
+func (s *Store) challengeRecord(account *Account, challengeId string) ChallengeRecord {
+	if account == nil {
+		return ChallengeRecord{}
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	stored, ok := s.data.Accounts[strings.ToLower(account.Id)]
+	if !ok {
+		return ChallengeRecord{}
+	}
+	record, ok := stored.Challenges[challengeId]
+	if !ok {
+		return ChallengeRecord{}
+	}
+	return *record
+}
@@ -0,0 +1,472 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const MinTournamentPlayers int = 2
+
+type TournamentFormat int
+
+const (
+	// Everyone plays every round against a player with the same score
+	Swiss TournamentFormat = iota
+	// The loser of a game leaves the tournament
+	SingleElimination
+)
+
+type Tournament struct {
+	mutex sync.Mutex
+
+	Name   string           `json:"name"`
+	Format TournamentFormat `json:"format"`
+	// The account which registered the tournament and may start it
+	Organizer string `json:"organizer"`
+	Gambits   bool   `json:"gambits"`
+	// Set for Swiss by the organizer, the brackets decide it otherwise
+	Rounds   int          `json:"rounds"`
+	Players  []string     `json:"players"`
+	Pairings [][]*Pairing `json:"pairings"`
+	Finished bool         `json:"finished"`
+}
+
+// Pairing is a game of the round, a single player is given a bye
+type Pairing struct {
+	Hub     string   `json:"hub,omitempty"`
+	Players []string `json:"players"`
+	Winner  string   `json:"winner,omitempty"`
+}
+
+type Standing struct {
+	Player string `json:"player"`
+	Points int    `json:"points"`
+	// Sum of the points of the opponents, breaks the ties of the points
+	Buchholz   int  `json:"buchholz"`
+	Wins       int  `json:"wins"`
+	Losses     int  `json:"losses"`
+	Byes       int  `json:"byes"`
+	Eliminated bool `json:"eliminated,omitempty"`
+}
+
+type WrongTournamentError struct {
+	reason string
+}
+
+func (e *WrongTournamentError) Error() string {
+	return fmt.Sprintf("wrong tournament: %s", e.reason)
+}
+
+var tournaments = make(map[string]*Tournament)
+var tournamentsMutex sync.Mutex
+
+func findTournament(name string) (*Tournament, bool) {
+	tournamentsMutex.Lock()
+	defer tournamentsMutex.Unlock()
+	tournament, ok := tournaments[name]
+	return tournament, ok
+}
+
+func addTournament(tournament *Tournament) bool {
+	tournamentsMutex.Lock()
+	defer tournamentsMutex.Unlock()
+	if _, ok := tournaments[tournament.Name]; ok {
+		return false
+	}
+	tournaments[tournament.Name] = tournament
+	return true
+}
+
+func listTournaments() []*Tournament {
+	tournamentsMutex.Lock()
+	defer tournamentsMutex.Unlock()
+	list := make([]*Tournament, 0, len(tournaments))
+	for _, tournament := range tournaments {
+		list = append(list, tournament)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+func newTournament(name string, format TournamentFormat, rounds int, organizer string, gambits bool) (*Tournament, error) {
+	if format != Swiss && format != SingleElimination {
+		return nil, &WrongTournamentError{fmt.Sprintf("unknown format %d", format)}
+	}
+	if format == Swiss && rounds < 1 {
+		return nil, &WrongTournamentError{"Swiss needs at least one round"}
+	}
+	return &Tournament{
+		Name:      name,
+		Format:    format,
+		Organizer: organizer,
+		Gambits:   gambits,
+		Rounds:    rounds,
+		Players:   []string{},
+		Pairings:  [][]*Pairing{},
+	}, nil
+}
+
+func (t *Tournament) register(account string) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if len(t.Pairings) > 0 {
+		return &WrongTournamentError{"the tournament has started"}
+	}
+	for _, player := range t.Players {
+		if player == account {
+			return &WrongTournamentError{"already registered"}
+		}
+	}
+	t.Players = append(t.Players, account)
+	return nil
+}
+
+func (t *Tournament) start(account string) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if account != t.Organizer {
+		return &WrongTournamentError{"only the organizer may start the tournament"}
+	}
+	if len(t.Pairings) > 0 {
+		return &WrongTournamentError{"the tournament has started"}
+	}
+	if len(t.Players) < MinTournamentPlayers {
+		return &WrongTournamentError{fmt.Sprintf("at least %d players are needed", MinTournamentPlayers)}
+	}
+	if t.Format == SingleElimination {
+		t.Rounds = int(math.Ceil(math.Log2(float64(len(t.Players)))))
+	}
+	// The next rounds take the hubs of the finished games, see nextRound
+	hubsMutex.Lock()
+	room := hasRoom(len(t.Players) / 2)
+	hubsMutex.Unlock()
+	if !room {
+		return &WrongTournamentError{fmt.Sprintf("the limit of %d hubs leaves no room for the games", config.MaxHubs)}
+	}
+	t.nextRound()
+	return nil
+}
+
+// recordResult is called by the hubs of the tournament when their game is
+// over, the next round starts once every game of the round is over
+func (t *Tournament) recordResult(hub string, winner string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if len(t.Pairings) == 0 || t.Finished {
+		return
+	}
+	round := t.Pairings[len(t.Pairings)-1]
+	for _, pairing := range round {
+		if pairing.Hub == hub && pairing.Winner == "" {
+			pairing.Winner = winner
+		}
+	}
+	for _, pairing := range round {
+		if pairing.Winner == "" {
+			return
+		}
+	}
+	if len(t.Pairings) >= t.Rounds {
+		t.Finished = true
+		return
+	}
+	t.nextRound()
+}
+
+// nextRound pairs the players and creates a hub for every game, the mutex
+// has to be held by the caller
+func (t *Tournament) nextRound() {
+	var round []*Pairing
+	if t.Format == Swiss {
+		round = t.swissPairings()
+	} else {
+		round = t.eliminationPairings()
+	}
+	t.Pairings = append(t.Pairings, round)
+	table := 0
+	for _, pairing := range round {
+		if len(pairing.Players) < 2 {
+			pairing.Winner = pairing.Players[0]
+			continue
+		}
+		table++
+		options := GameOptions{
+			Gambits: t.Gambits,
+			Players: MinPlayers,
+			Format:  FreeForAll,
+			Seed:    time.Now().UnixNano(),
+		}
+		err := resolveSetup(&options)
+		if err == nil {
+			err = t.startGame(pairing, table, options)
+		}
+		if err != nil {
+			slog.Error("pairing not started", "tournament", t.Name, "table", table, "err", err)
+		}
+	}
+}
+
+// startGame runs the hub of the pairing regardless of the limit of hubs, the
+// start of the tournament made room for its games. The name is numbered if a
+// player has taken it already.
+func (t *Tournament) startGame(pairing *Pairing, table int, options GameOptions) error {
+	access, err := newHubAccess(Public, "")
+	if err != nil {
+		return err
+	}
+	hubsMutex.Lock()
+	defer hubsMutex.Unlock()
+	name := fmt.Sprintf("%s_round%d_table%d", t.Name, len(t.Pairings), table)
+	pairing.Hub = name
+	for i := 2; hubs[pairing.Hub] != nil; i++ {
+		pairing.Hub = fmt.Sprintf("%s_%d", name, i)
+	}
+	hub := newHub(pairing.Hub, options, store)
+	hub.tournament = t
+	// The seats are taken by the paired accounts, anyone may watch
+	hub.access = access
+	for i, player := range pairing.Players {
+		hub.accounts[PlayerId(i+1)] = player
+	}
+	return runHub(pairing.Hub, hub)
+}
+
+// swissPairings pairs the players of the same standing who haven't played
+// each other yet, the lowest player without a bye gets one
+func (t *Tournament) swissPairings() []*Pairing {
+	standings := t.standings()
+	players := make([]string, 0, len(standings))
+	byes := make(map[string]bool)
+	for _, standing := range standings {
+		players = append(players, standing.Player)
+		byes[standing.Player] = standing.Byes > 0
+	}
+	round := []*Pairing{}
+	if len(players)%2 == 1 {
+		bye := len(players) - 1
+		for i := len(players) - 1; i >= 0; i-- {
+			if !byes[players[i]] {
+				bye = i
+				break
+			}
+		}
+		round = append(round, &Pairing{Players: []string{players[bye]}})
+		players = append(players[:bye], players[bye+1:]...)
+	}
+	played := t.played()
+	budget := pairingBudget
+	pairs, ok := pairSwiss(players, played, &budget)
+	if !ok {
+		// Everyone has met already or the search gave up, the fewest
+		// rematches are played then
+		pairs = pairGreedy(players, played)
+	}
+	for _, pair := range pairs {
+		round = append(round, &Pairing{Players: []string{pair[0], pair[1]}})
+	}
+	return round
+}
+
+// Bounds the backtracking, it is exponential when no pairing avoids the
+// rematches
+const pairingBudget int = 10000
+
+func pairSwiss(players []string, played map[string]map[string]bool, budget *int) ([][2]string, bool) {
+	if len(players) == 0 {
+		return [][2]string{}, true
+	}
+	if *budget <= 0 {
+		return nil, false
+	}
+	*budget--
+	first := players[0]
+	for i := 1; i < len(players); i++ {
+		if played[first][players[i]] {
+			continue
+		}
+		rest := append(append([]string{}, players[1:i]...), players[i+1:]...)
+		pairs, ok := pairSwiss(rest, played, budget)
+		if ok {
+			return append([][2]string{{first, players[i]}}, pairs...), true
+		}
+	}
+	return nil, false
+}
+
+// pairGreedy pairs every player with the closest player in the standings
+// they haven't met, or with the next one when they have met everyone
+func pairGreedy(players []string, played map[string]map[string]bool) [][2]string {
+	pairs := [][2]string{}
+	rest := append([]string{}, players...)
+	for len(rest) > 1 {
+		opponent := 1
+		for i := 1; i < len(rest); i++ {
+			if !played[rest[0]][rest[i]] {
+				opponent = i
+				break
+			}
+		}
+		pairs = append(pairs, [2]string{rest[0], rest[opponent]})
+		rest = append(rest[1:opponent], rest[opponent+1:]...)
+	}
+	return pairs
+}
+
+// eliminationPairings seeds the first round by the ratings so the best
+// players meet last, the next rounds pair the winners of the neighbouring
+// games
+func (t *Tournament) eliminationPairings() []*Pairing {
+	round := []*Pairing{}
+	if len(t.Pairings) > 0 {
+		previous := t.Pairings[len(t.Pairings)-1]
+		for i := 0; i+1 < len(previous); i += 2 {
+			round = append(round, &Pairing{Players: []string{previous[i].Winner, previous[i+1].Winner}})
+		}
+		return round
+	}
+	seeds := append([]string{}, t.Players...)
+	sort.SliceStable(seeds, func(i, j int) bool {
+		return rating(seeds[i]) > rating(seeds[j])
+	})
+	size := 1 << uint(t.Rounds)
+	order := bracketOrder(size)
+	for i := 0; i < size; i += 2 {
+		pairing := &Pairing{Players: []string{}}
+		// Seeds past the players are byes for their opponents
+		for _, seed := range order[i : i+2] {
+			if seed <= len(seeds) {
+				pairing.Players = append(pairing.Players, seeds[seed-1])
+			}
+		}
+		round = append(round, pairing)
+	}
+	return round
+}
+
+// bracketOrder lists the seeds so that the seeds 1 and 2 may meet only in
+// the final, e.g. 1, 8, 4, 5, 2, 7, 3, 6 for 8 players
+func bracketOrder(size int) []int {
+	order := []int{1}
+	for len(order) < size {
+		next := make([]int, 0, len(order)*2)
+		for _, seed := range order {
+			next = append(next, seed, len(order)*2+1-seed)
+		}
+		order = next
+	}
+	return order
+}
+
+func rating(account string) int {
+	found, ok := store.account(account)
+	if !ok {
+		return InitialRating
+	}
+	return found.Rating
+}
+
+func (t *Tournament) played() map[string]map[string]bool {
+	played := make(map[string]map[string]bool)
+	for _, player := range t.Players {
+		played[player] = make(map[string]bool)
+	}
+	for _, round := range t.Pairings {
+		for _, pairing := range round {
+			if len(pairing.Players) == 2 {
+				played[pairing.Players[0]][pairing.Players[1]] = true
+				played[pairing.Players[1]][pairing.Players[0]] = true
+			}
+		}
+	}
+	return played
+}
+
+// standings ranks the players by the points and then by the Buchholz
+// score, the mutex has to be held by the caller
+func (t *Tournament) standings() []Standing {
+	standings := make(map[string]*Standing)
+	for _, player := range t.Players {
+		standings[player] = &Standing{Player: player}
+	}
+	for _, round := range t.Pairings {
+		for _, pairing := range round {
+			if pairing.Winner == "" {
+				continue
+			}
+			if len(pairing.Players) < 2 {
+				standings[pairing.Winner].Byes++
+				standings[pairing.Winner].Points++
+				continue
+			}
+			for _, player := range pairing.Players {
+				if player == pairing.Winner {
+					standings[player].Wins++
+					standings[player].Points++
+				} else {
+					standings[player].Losses++
+					standings[player].Eliminated = t.Format == SingleElimination
+				}
+			}
+		}
+	}
+	for _, round := range t.Pairings {
+		for _, pairing := range round {
+			if len(pairing.Players) < 2 {
+				continue
+			}
+			standings[pairing.Players[0]].Buchholz += standings[pairing.Players[1]].Points
+			standings[pairing.Players[1]].Buchholz += standings[pairing.Players[0]].Points
+		}
+	}
+	result := make([]Standing, 0, len(standings))
+	for _, player := range t.Players {
+		result = append(result, *standings[player])
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Points != result[j].Points {
+			return result[i].Points > result[j].Points
+		}
+		return result[i].Buchholz > result[j].Buchholz
+	})
+	return result
+}
+
+// TournamentData is the published state of the tournament
+type TournamentData struct {
+	*Tournament
+	Standings []Standing `json:"standings"`
+}
+
+func (t *Tournament) data() TournamentData {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	// Copied so the json encoding doesn't race with the hubs
+	copied := &Tournament{
+		Name:      t.Name,
+		Format:    t.Format,
+		Organizer: t.Organizer,
+		Gambits:   t.Gambits,
+		Rounds:    t.Rounds,
+		Players:   append([]string{}, t.Players...),
+		Pairings:  make([][]*Pairing, 0, len(t.Pairings)),
+		Finished:  t.Finished,
+	}
+	for _, round := range t.Pairings {
+		pairings := make([]*Pairing, 0, len(round))
+		for _, pairing := range round {
+			copiedPairing := *pairing
+			pairings = append(pairings, &copiedPairing)
+		}
+		copied.Pairings = append(copied.Pairings, pairings)
+	}
+	return TournamentData{
+		Tournament: copied,
+		Standings:  t.standings(),
+	}
+}
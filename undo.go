@@ -0,0 +1,88 @@
+package main
+
+import "fmt"
+
+// Checkpoint is the game right before the last action of the player. It's
+// kept only while the action hasn't revealed any hidden information.
+type Checkpoint struct {
+	player       PlayerId
+	state        *State
+	allyState    *AllyState
+	deferredCall func() []StateAction
+}
+
+func (m *Middleware) checkpoint(player PlayerId, state *State) *Checkpoint {
+	return &Checkpoint{
+		player:       player,
+		state:        state.clone(),
+		allyState:    m.allyState.clone(),
+		deferredCall: m.deferredCall,
+	}
+}
+
+func (m *Middleware) undo(player PlayerId, state *State, actions *[]StateAction) error {
+	checkpoint := m.lastCheckpoint
+	if state.UndoDisabled || undoRefused(state, player) {
+		return &WrongMoveError{"undo is turned off"}
+	}
+	if checkpoint == nil || checkpoint.player != player {
+		return &WrongMoveError{"there is no action to undo"}
+	}
+	m.lastCheckpoint = nil
+	m.allyState = checkpoint.allyState
+	m.deferredCall = checkpoint.deferredCall
+	*actions = append(*actions, &StateActionRestoreState{
+		state: checkpoint.state,
+	})
+	m.log(LogEntry{
+		Player: player,
+		Action: Undo,
+		Text:   fmt.Sprintf("%s undid the last action", m.playerName(player)),
+	}, actions)
+	return nil
+}
+
+// undoRefused tells if another seat turned undo off
+func undoRefused(state *State, player PlayerId) bool {
+	for _, seat := range state.UndoRefused {
+		if seat != player {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *AllyState) clone() *AllyState {
+	clone := emptyAllyState()
+	for faction, flag := range a.flags {
+		clone.flags[faction] = flag
+	}
+	for faction, abilities := range a.abilities {
+		clone.abilities[faction] = append(CardAbilities{}, abilities...)
+	}
+	return clone
+}
+
+// revealsHiddenInformation reports whether the actions take cards from the
+// face-down decks or shuffle them
+func revealsHiddenInformation(actions []StateAction) bool {
+	for _, action := range actions {
+		switch a := action.(type) {
+		case *StateActionShuffleDeck:
+			return true
+		case *StateActionTopCard:
+			if isFaceDown(a.from) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isFaceDown(location CardLocation) bool {
+	if location == TradeDeck || location == GambitDeck {
+		return true
+	}
+	player, ok := ownerOf(location)
+	return ok && seatLocations[player].Deck == location
+}